@@ -0,0 +1,47 @@
+package httpx
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"rifa/backend/api/httpx/dto"
+	mymiddlewares "rifa/backend/api/httpx/middlewares"
+	"rifa/backend/internal/core/audit"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func RegisterAuditRoutes(api huma.API, db database.DB, opts config.ServiceOpts) {
+	srv := audit.NewService(db)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "listAuditLog",
+			Method:      http.MethodGet,
+			Path:        "/api/audit",
+			Summary:     "List admin actions (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.GetAuditLog,
+		) (*dto.AuditLogOutput, error) {
+			entries, total, err := srv.List(ctx, *input)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to get audit log",
+				)
+			}
+
+			return &dto.AuditLogOutput{Body: entries, Total: total}, nil
+		},
+	)
+}
@@ -2,6 +2,7 @@ package httpx
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"time"
@@ -34,6 +35,11 @@ func RegisterAuthRoutes(api huma.API, db database.DB, opts config.ServiceOpts) {
 			input *dto.RegisterInput,
 		) (*dto.RegisterOutput, error) {
 			err := srv.Register(ctx, &input.Body)
+			if errors.Is(err, auth.ErrInvalidReferralCode) {
+				return nil, huma.Error422UnprocessableEntity(
+					"El codigo de referido no existe",
+				)
+			}
 			if err != nil {
 				log.Printf("failed to register %v", err)
 				return nil, huma.Error400BadRequest(
@@ -0,0 +1,21 @@
+package httpx
+
+import (
+	"context"
+
+	"rifa/backend/internal/types"
+
+	chimdw "github.com/go-chi/chi/v5/middleware"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// actorFromContext builds the audit actor from the session claims, the client
+// address and the request id set by the router middlewares
+func actorFromContext(ctx context.Context) types.Actor {
+	actor := types.Actor{RequestID: chimdw.GetReqID(ctx)}
+	if claims, ok := ctx.Value("claims").(jwt.MapClaims); ok {
+		actor.ID, _ = claims["id"].(string)
+	}
+	actor.IP, _ = ctx.Value("remoteAddr").(string)
+	return actor
+}
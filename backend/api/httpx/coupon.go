@@ -0,0 +1,210 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/api/httpx/form"
+	mymiddlewares "rifa/backend/api/httpx/middlewares"
+	"rifa/backend/internal/core/coupon"
+	"rifa/backend/internal/core/price"
+	"rifa/backend/internal/types"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func RegisterCouponRoutes(
+	api huma.API,
+	db database.DB,
+	opts config.ServiceOpts,
+) {
+	srv := coupon.NewService(db, opts)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "validateCoupon",
+			Method:      http.MethodPost,
+			Path:        "/api/coupons/validate",
+			Summary:     "Check a coupon and quote the purchase with it applied",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.ValidateCouponInput,
+		) (*dto.CouponQuoteOutput, error) {
+			claims, ok := ctx.Value("claims").(jwt.MapClaims)
+			if !ok {
+				log.Println("No session claims")
+				return nil, huma.Error401Unauthorized("No session claims")
+			}
+
+			quote, err := srv.Quote(
+				ctx,
+				input.Body.Code,
+				claims["id"].(string),
+				input.Body.Quantity,
+			)
+			if err != nil {
+				log.Println(err)
+				return nil, couponError(err)
+			}
+
+			return &dto.CouponQuoteOutput{
+				Body: form.CouponQuote{
+					Code:         quote.Coupon.Code,
+					Type:         string(quote.Coupon.Type),
+					Quantity:     quote.Quantity,
+					BonusTickets: quote.BonusTickets,
+					DiscountBs:   quote.BsDiscount,
+					DiscountUSD:  quote.UsdDiscount,
+					BS:           quote.BsTotal,
+					USD:          quote.UsdTotal,
+				},
+			}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "listCoupons",
+			Method:      http.MethodGet,
+			Path:        "/api/admin/coupons",
+			Summary:     "List every coupon with its uses (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			_ *struct{},
+		) (*dto.CouponsOutput, error) {
+			coupons, err := srv.List(ctx)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to get coupons",
+				)
+			}
+
+			body := make([]form.Coupon, 0, len(coupons))
+			for _, c := range coupons {
+				body = append(body, form.Coupon{
+					Code: c.Code,
+					CouponRequest: form.CouponRequest{
+						Type:         string(c.Type),
+						Value:        c.Value,
+						MaxUses:      c.MaxUses,
+						PerUserLimit: c.PerUserLimit,
+						StartsAt:     c.StartsAt,
+						EndsAt:       c.EndsAt,
+						LotteryID:    c.LotteryID,
+						Enabled:      c.Enabled,
+					},
+					Uses: c.Uses,
+				})
+			}
+			return &dto.CouponsOutput{Body: body}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "createCoupon",
+			Method:      http.MethodPost,
+			Path:        "/api/admin/coupons",
+			Summary:     "Create a coupon (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusCreated,
+		},
+		func(
+			ctx context.Context,
+			input *dto.CreateCouponInput,
+		) (*struct{}, error) {
+			c := toCoupon(input.Body.Code, input.Body.CouponRequest)
+			err := srv.Create(ctx, actorFromContext(ctx), c)
+			if err != nil {
+				log.Println(err)
+				return nil, couponError(err)
+			}
+
+			return nil, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "updateCoupon",
+			Method:      http.MethodPut,
+			Path:        "/api/admin/coupons/{code}",
+			Summary:     "Update a coupon (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusNoContent,
+		},
+		func(
+			ctx context.Context,
+			input *dto.UpdateCouponInput,
+		) (*struct{}, error) {
+			c := toCoupon(input.Code, input.Body)
+			err := srv.Update(ctx, actorFromContext(ctx), c)
+			if err != nil {
+				log.Println(err)
+				return nil, couponError(err)
+			}
+
+			return nil, nil
+		},
+	)
+}
+
+func couponError(err error) error {
+	switch {
+	case errors.Is(err, coupon.ErrNotFound):
+		return huma.Error404NotFound("Cupon no encontrado")
+	case errors.Is(err, coupon.ErrAlreadyExists):
+		return huma.Error409Conflict("Coupon already exists")
+	case errors.Is(err, coupon.ErrInvalid):
+		return huma.Error422UnprocessableEntity(
+			"Percent coupons can't exceed 100, bonus tickets must be whole " +
+				"and the validity window must end after it starts",
+		)
+	case errors.Is(err, coupon.ErrUnavailable):
+		return huma.Error422UnprocessableEntity("Cupon no disponible")
+	case errors.Is(err, coupon.ErrExhausted):
+		return huma.Error422UnprocessableEntity("Cupon agotado")
+	case errors.Is(err, price.ErrNoPrices):
+		return huma.Error409Conflict("Los precios aun no estan disponibles")
+	}
+	return huma.Error500InternalServerError("Failed to process coupon")
+}
+
+func toCoupon(code string, req form.CouponRequest) types.Coupon {
+	return types.Coupon{
+		Code:         code,
+		Type:         types.CouponType(req.Type),
+		Value:        req.Value,
+		MaxUses:      req.MaxUses,
+		PerUserLimit: req.PerUserLimit,
+		StartsAt:     req.StartsAt,
+		EndsAt:       req.EndsAt,
+		LotteryID:    req.LotteryID,
+		Enabled:      req.Enabled,
+	}
+}
@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"time"
+
+	"rifa/backend/api/httpx/form"
+)
+
+type GetAuditLog struct {
+	ActorID    string    `query:"actor" format:"uuid"`
+	TargetType string    `query:"targetType"`
+	TargetID   string    `query:"target"`
+	From       time.Time `query:"from" doc:"RFC3339 lower bound (inclusive)"`
+	To         time.Time `query:"to" doc:"RFC3339 upper bound (exclusive)"`
+	Page       int       `query:"page" doc:"pagination value"`
+	ItemCount  int       `query:"perPage"`
+}
+
+type AuditLogOutput struct {
+	Body  []form.AuditEntry
+	Total int `header:"X-Total-Count"`
+}
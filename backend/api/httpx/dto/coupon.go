@@ -0,0 +1,27 @@
+package dto
+
+import "rifa/backend/api/httpx/form"
+
+type CouponsOutput struct {
+	Body []form.Coupon
+}
+
+type CreateCouponInput struct {
+	Body struct {
+		Code string `json:"code" minLength:"1" maxLength:"50"`
+		form.CouponRequest
+	}
+}
+
+type UpdateCouponInput struct {
+	Code string `path:"code"`
+	Body form.CouponRequest
+}
+
+type ValidateCouponInput struct {
+	Body form.CouponValidation
+}
+
+type CouponQuoteOutput struct {
+	Body form.CouponQuote
+}
@@ -0,0 +1,23 @@
+package dto
+
+import "rifa/backend/api/httpx/form"
+
+type RecordExchangeRateInput struct {
+	Body struct {
+		Rate float64 `json:"rate" exclusiveMinimum:"0" doc:"Bs per USD"`
+	}
+}
+
+type ExchangeRateOutput struct {
+	Body form.ExchangeRate
+}
+
+type GetExchangeRates struct {
+	Page      int `query:"page" doc:"pagination value"`
+	ItemCount int `query:"perPage"`
+}
+
+type ExchangeRatesOutput struct {
+	Body  []form.ExchangeRate
+	Total int `header:"X-Total-Count"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+import "rifa/backend/api/httpx/form"
+
+type LotteryOutput struct {
+	Body form.LotteryConfig
+}
+
+type UpdateLotteryLimitsInput struct {
+	Body form.LotteryLimits
+}
@@ -0,0 +1,23 @@
+package dto
+
+import "rifa/backend/api/httpx/form"
+
+type PaymentMethodsOutput struct {
+	Body []form.PaymentMethod
+}
+
+type CreatePaymentMethodInput struct {
+	Body struct {
+		Code string `json:"code" minLength:"1" maxLength:"50"`
+		form.PaymentMethodRequest
+	}
+}
+
+type UpdatePaymentMethodInput struct {
+	Code string `path:"code"`
+	Body form.PaymentMethodRequest
+}
+
+type DeletePaymentMethodInput struct {
+	Code string `path:"code"`
+}
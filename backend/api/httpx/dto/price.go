@@ -7,5 +7,19 @@ type PriceOutput struct {
 }
 
 type PriceUpdateInput struct {
-	Body form.LotteryPrices
+	Body form.PriceUpdate
+}
+
+type PriceTiersInput struct {
+	Body []form.PriceTier `maxItems:"50"`
+}
+
+type GetPriceHistory struct {
+	Page      int `query:"page" doc:"pagination value"`
+	ItemCount int `query:"perPage"`
+}
+
+type PriceHistoryOutput struct {
+	Body  []form.PriceHistoryEntry
+	Total int `header:"X-Total-Count"`
 }
@@ -1,6 +1,8 @@
 package dto
 
 import (
+	"time"
+
 	"rifa/backend/api/httpx/form"
 
 	"github.com/danielgtaylor/huma/v2"
@@ -18,31 +20,87 @@ type PurchaseOutput struct {
 }
 
 type PurchasesOutput struct {
-	Body  []form.Purchases
-	Total int `header:"X-Total-Count"`
+	Body       []form.Purchases
+	Total      int    `header:"X-Total-Count"`
+	NextCursor string `header:"X-Next-Cursor"`
 }
 type MostPurchasesOutput struct {
-	Body []form.MostPurchases
+	Body  []form.MostPurchases
+	Total int `header:"X-Total-Count"`
+}
+
+// PurchaseFilters narrows the purchases listed or exported by the admin
+type PurchaseFilters struct {
+	PurchaseStatus string    `query:"status"`
+	Flagged        bool      `query:"flagged" doc:"only duplicated or mispriced purchases"`
+	From           time.Time `query:"from" doc:"RFC3339 lower bound (inclusive)"`
+	To             time.Time `query:"to" doc:"RFC3339 upper bound (exclusive)"`
+	PaymentMethod  string    `query:"paymentMethod"`
+	LotteryID      string    `query:"lottery"`
+	Email          string    `query:"email" maxLength:"100" doc:"part of the buyer email"`
+	Phone          string    `query:"phone" maxLength:"30" doc:"part of the buyer phone"`
+	Reference      string    `query:"reference" maxLength:"20" doc:"transaction digits"`
+	Ticket         string    `query:"ticket" pattern:"^[0-9]{1,4}$" doc:"ticket number held by the purchase"`
+	Currency       string    `query:"currency" enum:"bs,usd" default:"usd" doc:"currency of minAmount and maxAmount"`
+	MinAmount      float64   `query:"minAmount" minimum:"0"`
+	MaxAmount      float64   `query:"maxAmount" minimum:"0"`
 }
 
 type GetAllPurchases struct {
-	PurchaseStatus string `query:"status"`
-	Page           int    `query:"page" doc:"pagination value"`
-	ItemCount      int    `query:"perPage"`
+	PurchaseFilters
+	Sort      string `query:"sort" enum:"date,amountBs,amountUsd,quantity" default:"date"`
+	Order     string `query:"order" enum:"asc,desc" default:"desc"`
+	Cursor    string `query:"cursor" doc:"X-Next-Cursor of the previous page, replaces page"`
+	Page      int    `query:"page" doc:"pagination value"`
+	ItemCount int    `query:"perPage"`
+}
+
+type ExportPurchases struct {
+	PurchaseFilters
+	Format string `query:"format" enum:"csv,xlsx" default:"csv"`
+}
+
+type ExportPurchasesOutput struct {
+	ContentType        string `header:"Content-Type"`
+	ContentDisposition string `header:"Content-Disposition"`
+	Body               func(ctx huma.Context)
 }
 
 type GetMostPurchases struct {
-	Page      int `query:"page" doc:"pagination value"`
-	ItemCount int `query:"perPage"`
+	LotteryID string    `query:"lottery" format:"uuid" doc:"defaults to the active lottery"`
+	From      time.Time `query:"from" doc:"RFC3339 lower bound (inclusive)"`
+	To        time.Time `query:"to" doc:"RFC3339 upper bound (exclusive)"`
+	Page      int       `query:"page" doc:"pagination value"`
+	ItemCount int       `query:"perPage"`
+}
+
+type GetPublicLeaderboard struct {
+	Limit int `query:"limit" minimum:"1" maximum:"50" default:"10"`
+}
+
+type PublicLeaderboardOutput struct {
+	Body []form.PublicLeader
 }
 
 type UpdatePurchase struct {
 	ID   string `query:"id"`
 	Body struct {
-		Status string `json:"status"`
+		Status string `json:"status" enum:"pending,verified,cancelled"`
 	}
 }
 
+type BulkUpdatePurchases struct {
+	Body struct {
+		IDs    []string `json:"ids" minItems:"1" maxItems:"200"`
+		Status string   `json:"status" enum:"pending,verified,cancelled"`
+		Reason string   `json:"reason,omitempty" maxLength:"500"`
+	}
+}
+
+type BulkUpdatePurchasesOutput struct {
+	Body form.BulkStatusResponse
+}
+
 type SearchPurchase struct {
 	Number string `query:"number"`
 }
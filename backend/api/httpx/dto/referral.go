@@ -0,0 +1,17 @@
+package dto
+
+import "rifa/backend/api/httpx/form"
+
+type ReferralSummaryOutput struct {
+	Body form.ReferralSummary
+}
+
+type GetTopReferrers struct {
+	Page      int `query:"page" doc:"pagination value"`
+	ItemCount int `query:"perPage"`
+}
+
+type TopReferrersOutput struct {
+	Body  []form.TopReferrer
+	Total int `header:"X-Total-Count"`
+}
@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"rifa/backend/api/httpx/form"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type CreateRefundInput struct {
+	PurchaseID string `path:"id" format:"uuid"`
+	Body       form.RefundRequest
+}
+
+type CompleteRefundInput struct {
+	ID      string `path:"id" format:"uuid"`
+	RawBody huma.MultipartFormFiles[struct {
+		Proof     huma.FormFile `form:"proof" contentType:"image/*"`
+		Reference string        `form:"reference"`
+	}]
+}
+
+type RefundOutput struct {
+	Body form.Refund
+}
+
+type GetRefunds struct {
+	Status     string `query:"status" doc:"pending or completed"`
+	PurchaseID string `query:"purchase"`
+	Page       int    `query:"page" doc:"pagination value"`
+	ItemCount  int    `query:"perPage"`
+}
+
+type RefundsOutput struct {
+	Body  []form.Refund
+	Total int `header:"X-Total-Count"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+import (
+	"time"
+
+	"rifa/backend/api/httpx/form"
+)
+
+type GetRevenueReport struct {
+	LotteryID string    `query:"lottery" format:"uuid" doc:"defaults to the active lottery"`
+	From      time.Time `query:"from" doc:"RFC3339 lower bound (inclusive)"`
+	To        time.Time `query:"to" doc:"RFC3339 upper bound (exclusive)"`
+	GroupBy   string    `query:"groupBy" enum:"day,week" default:"day"`
+}
+
+type RevenueReportOutput struct {
+	Body form.RevenueReport
+}
+
+type GetSalesVelocity struct {
+	Interval string `query:"interval" enum:"hour,day" default:"day"`
+	Window   int    `query:"window" minimum:"1" maximum:"168" default:"7" doc:"intervals averaged by the moving average"`
+	Periods  int    `query:"periods" minimum:"1" maximum:"720" default:"30" doc:"latest intervals returned"`
+}
+
+type SalesVelocityOutput struct {
+	Body form.SalesVelocity
+}
@@ -0,0 +1,12 @@
+package dto
+
+import "rifa/backend/api/httpx/form"
+
+type AdminSearch struct {
+	Query string `query:"q" required:"true" minLength:"2" maxLength:"100"`
+	Limit int    `query:"limit" minimum:"1" maximum:"50" default:"20"`
+}
+
+type AdminSearchOutput struct {
+	Body []form.SearchHit
+}
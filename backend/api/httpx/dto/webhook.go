@@ -0,0 +1,41 @@
+package dto
+
+import "rifa/backend/api/httpx/form"
+
+type WebhookIDInput struct {
+	ID string `path:"id" format:"uuid"`
+}
+
+type CreateWebhookInput struct {
+	Body form.WebhookRequest
+}
+
+type UpdateWebhookInput struct {
+	ID   string `path:"id" format:"uuid"`
+	Body form.WebhookRequest
+}
+
+type WebhookOutput struct {
+	Body form.Webhook
+}
+
+type WebhooksOutput struct {
+	Body []form.Webhook
+}
+
+type GetWebhookDeliveries struct {
+	ID        string `path:"id" format:"uuid"`
+	Status    string `query:"status" doc:"pending, succeeded or failed"`
+	Event     string `query:"event"`
+	Page      int    `query:"page" doc:"pagination value"`
+	ItemCount int    `query:"perPage"`
+}
+
+type WebhookDeliveryOutput struct {
+	Body form.WebhookDelivery
+}
+
+type WebhookDeliveriesOutput struct {
+	Body  []form.WebhookDelivery
+	Total int `header:"X-Total-Count"`
+}
@@ -0,0 +1,128 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"rifa/backend/api/httpx/form"
+	mymiddlewares "rifa/backend/api/httpx/middlewares"
+	"rifa/backend/internal/events"
+	"rifa/backend/pkg/config"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/sse"
+)
+
+// adminEventBuffer is how many events a slow admin stream can fall behind
+// before new ones are dropped for it
+const adminEventBuffer = 32
+
+func RegisterEventRoutes(api huma.API, bus *events.Bus, opts config.ServiceOpts) {
+	sse.Register(
+		api,
+		huma.Operation{
+			OperationID: "adminEventsStream",
+			Method:      http.MethodGet,
+			Path:        "/api/admin/events/stream",
+			Summary:     "Stream purchase and price changes (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+		},
+		map[string]any{
+			events.NamePurchaseCreated:       form.PurchaseCreatedEvent{},
+			events.NamePurchaseStatusChanged: form.PurchaseStatusEvent{},
+			events.NamePriceChanged:          form.PriceChangedEvent{},
+			"ping":                           form.StreamPing{},
+		},
+		func(ctx context.Context, _ *struct{}, send sse.Sender) {
+			messages := make(chan any, adminEventBuffer)
+			unsubscribe := bus.Subscribe(func(_ context.Context, msg events.Message) {
+				message := adminEventMessage(msg)
+				if message == nil {
+					return
+				}
+				// Publishing must never wait on a client
+				select {
+				case messages <- message:
+				default:
+				}
+			})
+			defer unsubscribe()
+
+			ping := time.NewTicker(streamPingInterval)
+			defer ping.Stop()
+			var err error
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case message := <-messages:
+					err = send.Data(message)
+				case now := <-ping.C:
+					err = send.Data(form.StreamPing{Time: now})
+				}
+				if err != nil {
+					return
+				}
+			}
+		},
+	)
+}
+
+// adminEventMessage returns the body sent to admins for msg, or nil when its
+// event isn't streamed
+func adminEventMessage(msg events.Message) any {
+	switch e := msg.Event.(type) {
+	case events.PurchaseCreated:
+		p := e.Purchase
+		return form.PurchaseCreatedEvent{
+			ID:             p.ID,
+			UserID:         p.UserID,
+			LotteryID:      p.LotteryID,
+			Quantity:       p.Quantity,
+			BonusTickets:   p.BonusTickets,
+			MontoBs:        p.MontoBs,
+			MontoUSD:       p.MontoUSD,
+			PaymentMethod:  p.PaymentMethod,
+			Status:         string(p.Status),
+			DuplicateOf:    p.DuplicateOf,
+			AmountMismatch: p.AmountMismatch,
+			CreatedAt:      p.CreatedAt,
+		}
+	case events.StatusEvent:
+		change := e.Change()
+		return form.PurchaseStatusEvent{
+			ID:        change.Purchase.ID,
+			UserID:    change.Purchase.UserID,
+			LotteryID: change.Purchase.LotteryID,
+			From:      string(change.From),
+			To:        string(change.Purchase.Status),
+			Reason:    change.Reason,
+			ChangedBy: change.Actor.ID,
+			ChangedAt: msg.OccurredAt,
+		}
+	case events.PriceChanged:
+		tiers := make([]form.PriceTier, 0, len(e.Tiers))
+		for _, tier := range e.Tiers {
+			tiers = append(tiers, form.PriceTier{
+				Quantity:     tier.Quantity,
+				PaidQuantity: tier.PaidQuantity,
+				BS:           tier.BsAmount,
+				USD:          tier.UsdAmount,
+			})
+		}
+		return form.PriceChangedEvent{
+			Cause:         string(e.Cause),
+			ID:            e.Prices.ID,
+			LotteryID:     e.Prices.LotteryID,
+			BS:            e.Prices.BsAmount,
+			USD:           e.Prices.UsdAmount,
+			EffectiveFrom: e.Prices.EffectiveFrom,
+			ChangedBy:     e.Actor.ID,
+			Tiers:         tiers,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/api/httpx/form"
+	mymiddlewares "rifa/backend/api/httpx/middlewares"
+	"rifa/backend/internal/core/exchange"
+	"rifa/backend/internal/events"
+	"rifa/backend/internal/types"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func RegisterExchangeRoutes(
+	api huma.API,
+	db database.DB,
+	recorder events.Recorder,
+	opts config.ServiceOpts,
+) {
+	srv := exchange.NewService(db, recorder, opts)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "listExchangeRates",
+			Method:      http.MethodGet,
+			Path:        "/api/exchange-rates",
+			Summary:     "List the recorded USD/VES rates (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.GetExchangeRates,
+		) (*dto.ExchangeRatesOutput, error) {
+			rates, total, err := srv.List(ctx, input.Page, input.ItemCount)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to get exchange rates",
+				)
+			}
+
+			body := make([]form.ExchangeRate, 0, len(rates))
+			for _, rate := range rates {
+				body = append(body, toExchangeRateResponse(rate))
+			}
+			return &dto.ExchangeRatesOutput{Body: body, Total: total}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "recordExchangeRate",
+			Method:      http.MethodPost,
+			Path:        "/api/exchange-rates",
+			Summary:     "Record the day's USD/VES rate (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusCreated,
+		},
+		func(
+			ctx context.Context,
+			input *dto.RecordExchangeRateInput,
+		) (*dto.ExchangeRateOutput, error) {
+			rate, err := srv.Record(ctx, actorFromContext(ctx), input.Body.Rate)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to save exchange rate",
+				)
+			}
+
+			return &dto.ExchangeRateOutput{
+				Body: toExchangeRateResponse(rate),
+			}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "refreshExchangeRate",
+			Method:      http.MethodPost,
+			Path:        "/api/exchange-rates/refresh",
+			Summary:     "Fetch and record the rate from the provider (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusCreated,
+		},
+		func(
+			ctx context.Context,
+			_ *struct{},
+		) (*dto.ExchangeRateOutput, error) {
+			rate, err := srv.Refresh(ctx, actorFromContext(ctx))
+			if err != nil {
+				log.Println(err)
+				if errors.Is(err, exchange.ErrNoProvider) {
+					return nil, huma.Error409Conflict(
+						"No exchange rate provider configured",
+					)
+				}
+				return nil, huma.Error502BadGateway(
+					"Failed to fetch exchange rate",
+				)
+			}
+
+			return &dto.ExchangeRateOutput{
+				Body: toExchangeRateResponse(rate),
+			}, nil
+		},
+	)
+}
+
+func toExchangeRateResponse(rate types.ExchangeRate) form.ExchangeRate {
+	return form.ExchangeRate{
+		ID:        rate.ID,
+		Rate:      rate.Rate,
+		Source:    rate.Source,
+		CreatedBy: rate.CreatedBy,
+		CreatedAt: rate.CreatedAt,
+	}
+}
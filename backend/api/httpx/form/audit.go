@@ -0,0 +1,19 @@
+package form
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditEntry struct {
+	ID         string          `json:"id"`
+	ActorID    *string         `json:"actorId"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"targetType"`
+	TargetID   string          `json:"targetId"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	IP         *string         `json:"ip"`
+	RequestID  *string         `json:"requestId"`
+	CreatedAt  time.Time       `json:"date"`
+}
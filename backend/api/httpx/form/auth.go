@@ -5,6 +5,8 @@ type RegisterRequest struct {
 	Email    string `json:"email" required:"true"`
 	Phone    string `json:"phone" required:"true"`
 	Password string `json:"password" required:"true"`
+	// ReferralCode is the code of the user who invited them, if any
+	ReferralCode string `json:"referralCode,omitempty" required:"false" maxLength:"20"`
 }
 
 type LoginRequest struct {
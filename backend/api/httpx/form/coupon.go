@@ -0,0 +1,36 @@
+package form
+
+import "time"
+
+type Coupon struct {
+	Code string `json:"code"`
+	CouponRequest
+	Uses int `json:"uses" doc:"redemptions by purchases that weren't cancelled or refunded"`
+}
+
+type CouponRequest struct {
+	Type         string     `json:"type" enum:"percent,fixed,bonus_tickets"`
+	Value        float64    `json:"value" exclusiveMinimum:"0" doc:"percentage off, USD off or bonus tickets depending on the type"`
+	MaxUses      *int       `json:"maxUses,omitempty" minimum:"1"`
+	PerUserLimit *int       `json:"perUserLimit,omitempty" minimum:"1"`
+	StartsAt     *time.Time `json:"startsAt,omitempty"`
+	EndsAt       *time.Time `json:"endsAt,omitempty"`
+	LotteryID    *string    `json:"lotteryId,omitempty" format:"uuid" doc:"restrict the coupon to this lottery"`
+	Enabled      bool       `json:"enabled"`
+}
+
+type CouponValidation struct {
+	Code     string `json:"code" minLength:"1" maxLength:"50"`
+	Quantity int    `json:"quantity" minimum:"1" maximum:"10000"`
+}
+
+type CouponQuote struct {
+	Code         string  `json:"code"`
+	Type         string  `json:"type"`
+	Quantity     int     `json:"quantity"`
+	BonusTickets int     `json:"bonusTickets"`
+	DiscountBs   float64 `json:"descuentoBs"`
+	DiscountUSD  float64 `json:"descuentoUsd"`
+	BS           float64 `json:"montoBs"`
+	USD          float64 `json:"montoUsd"`
+}
@@ -0,0 +1,40 @@
+package form
+
+import "time"
+
+type PurchaseCreatedEvent struct {
+	ID             string    `json:"id"`
+	UserID         string    `json:"userId"`
+	LotteryID      string    `json:"lotteryId"`
+	Quantity       int       `json:"quantity"`
+	BonusTickets   int       `json:"bonusTickets"`
+	MontoBs        float64   `json:"montoBs"`
+	MontoUSD       float64   `json:"montoUsd"`
+	PaymentMethod  string    `json:"paymentMethod"`
+	Status         string    `json:"status"`
+	DuplicateOf    *string   `json:"duplicateOf,omitempty"`
+	AmountMismatch bool      `json:"amountMismatch"`
+	CreatedAt      time.Time `json:"date"`
+}
+
+type PurchaseStatusEvent struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	LotteryID string    `json:"lotteryId"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Reason    string    `json:"reason,omitempty"`
+	ChangedBy string    `json:"changedById,omitempty" doc:"empty when the purchase expired"`
+	ChangedAt time.Time `json:"date"`
+}
+
+type PriceChangedEvent struct {
+	Cause         string      `json:"cause" enum:"prices,scheduled,tiers,rate"`
+	ID            string      `json:"id"`
+	LotteryID     string      `json:"lotteryId"`
+	BS            float64     `json:"montoBs"`
+	USD           float64     `json:"montoUsd"`
+	EffectiveFrom time.Time   `json:"effectiveFrom"`
+	ChangedBy     string      `json:"changedById,omitempty"`
+	Tiers         []PriceTier `json:"tiers,omitempty" doc:"set when the bundles changed"`
+}
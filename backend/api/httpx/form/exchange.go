@@ -0,0 +1,11 @@
+package form
+
+import "time"
+
+type ExchangeRate struct {
+	ID        string    `json:"id"`
+	Rate      float64   `json:"rate"`
+	Source    string    `json:"source"`
+	CreatedBy *string   `json:"createdBy,omitempty"`
+	CreatedAt time.Time `json:"date"`
+}
@@ -0,0 +1,16 @@
+package form
+
+type LotteryConfig struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	LotteryLimits
+}
+
+type LotteryLimits struct {
+	MinPerPurchase int  `json:"minPerPurchase" minimum:"1"`
+	MaxPerPurchase *int `json:"maxPerPurchase,omitempty" minimum:"1" doc:"no maximum when omitted"`
+	MaxPerUser     *int `json:"maxPerUser,omitempty" minimum:"1" doc:"pending and verified tickets a user may hold, no maximum when omitted"`
+	// PendingExpiryHours lets buyers know how long they have to get their
+	// payment verified
+	PendingExpiryHours *int `json:"pendingExpiryHours,omitempty" minimum:"1" doc:"hours before an unverified purchase is cancelled, never when omitted"`
+}
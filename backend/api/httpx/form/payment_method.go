@@ -0,0 +1,18 @@
+package form
+
+type PaymentMethod struct {
+	Code             string `json:"code"`
+	DisplayName      string `json:"name"`
+	Currency         string `json:"currency" enum:"bs,usd"`
+	AccountDetails   string `json:"accountDetails"`
+	Enabled          bool   `json:"enabled"`
+	ReferencePattern string `json:"referencePattern" doc:"regular expression the transaction digits must match"`
+}
+
+type PaymentMethodRequest struct {
+	DisplayName      string `json:"name" minLength:"1"`
+	Currency         string `json:"currency" enum:"bs,usd"`
+	AccountDetails   string `json:"accountDetails"`
+	Enabled          bool   `json:"enabled"`
+	ReferencePattern string `json:"referencePattern" minLength:"1" doc:"regular expression the transaction digits must match, anchored with ^ and $; references are at most 64 characters"`
+}
@@ -1,6 +1,33 @@
 package form
 
+import "time"
+
 type LotteryPrices struct {
-	BS  float64 `json:"montoBs"`
-	USD float64 `json:"montoUsd"`
+	BS    float64     `json:"montoBs"`
+	USD   float64     `json:"montoUsd"`
+	Tiers []PriceTier `json:"tiers"`
+}
+
+type PriceTier struct {
+	Quantity     int      `json:"quantity" minimum:"2"`
+	PaidQuantity *int     `json:"paidQuantity,omitempty" minimum:"1" doc:"sell the bundle for this many unit prices"`
+	BS           *float64 `json:"montoBs,omitempty" exclusiveMinimum:"0" doc:"fixed price of the bundle, set along with montoUsd"`
+	USD          *float64 `json:"montoUsd,omitempty" exclusiveMinimum:"0"`
+}
+
+type PriceUpdate struct {
+	BS            float64    `json:"montoBs"`
+	USD           float64    `json:"montoUsd"`
+	EffectiveFrom *time.Time `json:"effectiveFrom,omitempty" doc:"schedule the prices from this date instead of right away"`
+}
+
+type PriceHistoryEntry struct {
+	ID            string    `json:"id"`
+	BS            float64   `json:"montoBs"`
+	USD           float64   `json:"montoUsd"`
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+	Scheduled     bool      `json:"scheduled"`
+	ChangedByID   *string   `json:"changedById,omitempty"`
+	ChangedBy     *string   `json:"changedBy,omitempty"`
+	CreatedAt     time.Time `json:"date"`
 }
@@ -15,9 +15,10 @@ type Form struct {
 	MontoBs           string        `form:"montoBs"`
 	MontoUSD          string        `form:"montoUSD"`
 	PaymentMethod     string        `form:"paymentMethod" validate:"required"`
-	TransactionDigits string        `form:"transactionDigits" validate:"len>=6"`
+	TransactionDigits string        `form:"transactionDigits" validate:"required"`
 	SelectedNumbers   string        `form:"selectedNumbers"`
 	PaymentScreenshot huma.FormFile `form:"paymentScreenshot" validate:"required"`
+	Coupon            string        `form:"coupon"`
 }
 
 type CreatePurchaseRequest struct {
@@ -29,20 +30,26 @@ type CreatePurchaseRequest struct {
 	TransactionDigits string
 	SelectedNumbers   []string
 	PaymentScreenshot []byte
+	CouponCode        string
 }
 
 type Purchases struct {
-	User              User      `json:"user"`
-	ID                string    `json:"id"`
-	Quantity          int       `json:"quantity"`
-	Tickets           []string  `json:"tickets"`
-	MontoBs           float64   `json:"montoBs"`
-	MontoUSD          float64   `json:"montoUsd"`
-	PaymentMethod     string    `json:"paymentMethod"`
-	TransactionDigits string    `json:"transactionDigits"`
-	Status            string    `json:"status"`
-	PaymentScreenshot []byte    `json:"paymentScreenshot"`
-	CreatedAt         time.Time `json:"date"`
+	User               User      `json:"user"`
+	ID                 string    `json:"id"`
+	Quantity           int       `json:"quantity"`
+	Tickets            []string  `json:"tickets"`
+	MontoBs            float64   `json:"montoBs"`
+	MontoUSD           float64   `json:"montoUsd"`
+	PaymentMethod      string    `json:"paymentMethod"`
+	TransactionDigits  string    `json:"transactionDigits"`
+	Status             string    `json:"status"`
+	PaymentScreenshot  []byte    `json:"paymentScreenshot"`
+	DuplicateOf        *string   `json:"duplicateOf,omitempty"`
+	AmountMismatch     bool      `json:"amountMismatch"`
+	SimilarScreenshots []string  `json:"similarScreenshots"`
+	Coupon             *string   `json:"coupon,omitempty"`
+	BonusTickets       int       `json:"bonusTickets"`
+	CreatedAt          time.Time `json:"date"`
 }
 
 type User struct {
@@ -54,10 +61,26 @@ type User struct {
 
 type MostPurchases struct {
 	User     User `json:"user"`
-	Quantity int  `json:"quantity"`
+	Quantity int  `json:"quantity" doc:"tickets of verified purchases"`
+	Pending  int  `json:"pending" doc:"tickets of purchases pending verification"`
+}
+
+type PublicLeader struct {
+	Position int    `json:"position"`
+	Name     string `json:"name" doc:"masked buyer name"`
+	Quantity int    `json:"quantity"`
 }
 
 type SearchResult struct {
 	User    *User    `json:"user,omitempty"`
 	Tickets []string `json:"tickets,omitempty"`
 }
+
+type StatusChangeResult struct {
+	ID     string `json:"id"`
+	Result string `json:"result" enum:"applied,not_found,illegal_transition"`
+}
+
+type BulkStatusResponse struct {
+	Results []StatusChangeResult `json:"results"`
+}
@@ -0,0 +1,15 @@
+package form
+
+type ReferralSummary struct {
+	Code          string   `json:"code"`
+	Referred      int      `json:"referred" doc:"users registered with the code"`
+	Converted     int      `json:"converted" doc:"referred users with a verified purchase"`
+	RewardTickets []string `json:"rewardTickets"`
+}
+
+type TopReferrer struct {
+	User          User `json:"user"`
+	Referred      int  `json:"referred"`
+	Converted     int  `json:"converted"`
+	RewardTickets int  `json:"rewardTickets"`
+}
@@ -0,0 +1,28 @@
+package form
+
+import "time"
+
+type RefundRequest struct {
+	Amount   float64 `json:"amount" exclusiveMinimum:"0"`
+	Currency string  `json:"currency" enum:"bs,usd"`
+	Method   string  `json:"method" minLength:"1" maxLength:"50" doc:"how the money is sent back, e.g. pago movil"`
+	Reason   string  `json:"reason,omitempty" maxLength:"500"`
+	Full     bool    `json:"full" doc:"move the purchase to refunded, releasing its tickets, once completed"`
+}
+
+type Refund struct {
+	ID          string     `json:"id"`
+	PurchaseID  string     `json:"purchaseId"`
+	Amount      float64    `json:"amount"`
+	Currency    string     `json:"currency"`
+	Method      string     `json:"method"`
+	Reference   string     `json:"reference"`
+	Reason      string     `json:"reason"`
+	Full        bool       `json:"full"`
+	Status      string     `json:"status"`
+	Proof       []byte     `json:"proof,omitempty"`
+	CreatedBy   *string    `json:"createdBy,omitempty"`
+	CompletedBy *string    `json:"completedBy,omitempty"`
+	CreatedAt   time.Time  `json:"date"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
@@ -0,0 +1,59 @@
+package form
+
+import "time"
+
+type RevenueTotals struct {
+	Purchases int     `json:"purchases"`
+	Tickets   int     `json:"tickets"`
+	MontoBs   float64 `json:"montoBs" doc:"amounts of purchases paid in Bs"`
+	MontoUSD  float64 `json:"montoUsd" doc:"amounts of purchases paid in USD"`
+}
+
+type RevenuePeriod struct {
+	Period time.Time `json:"period" doc:"start of the day or week"`
+	RevenueTotals
+}
+
+type RevenueByMethod struct {
+	PaymentMethod string `json:"paymentMethod"`
+	RevenueTotals
+}
+
+type RevenueByStatus struct {
+	Status string `json:"status"`
+	RevenueTotals
+}
+
+type RefundTotals struct {
+	Refunds   int     `json:"refunds"`
+	AmountBs  float64 `json:"amountBs"`
+	AmountUSD float64 `json:"amountUsd"`
+}
+
+type RevenueReport struct {
+	LotteryID       string            `json:"lotteryId"`
+	Verified        RevenueTotals     `json:"verified"`
+	Pending         RevenueTotals     `json:"pending"`
+	Refunded        RefundTotals      `json:"refunded" doc:"completed refunds, full and partial"`
+	ByPeriod        []RevenuePeriod   `json:"byPeriod" doc:"verified purchases"`
+	ByPaymentMethod []RevenueByMethod `json:"byPaymentMethod" doc:"verified purchases"`
+	ByStatus        []RevenueByStatus `json:"byStatus"`
+}
+
+type SalesPeriod struct {
+	Period        time.Time `json:"period" doc:"start of the hour or day"`
+	Sold          int       `json:"sold"`
+	Reserved      int       `json:"reserved" doc:"tickets of purchases pending verification"`
+	MovingAverage float64   `json:"movingAverage"`
+}
+
+type SalesVelocity struct {
+	LotteryID        string        `json:"lotteryId"`
+	Interval         string        `json:"interval"`
+	Sold             int           `json:"sold"`
+	Reserved         int           `json:"reserved"`
+	Available        int           `json:"available"`
+	Rate             float64       `json:"rate" doc:"tickets sold or reserved per interval, latest moving average"`
+	ProjectedSellOut *time.Time    `json:"projectedSellOut,omitempty" doc:"missing when no tickets are selling"`
+	Periods          []SalesPeriod `json:"periods"`
+}
@@ -0,0 +1,20 @@
+package form
+
+import "time"
+
+type SearchHit struct {
+	Type     string          `json:"type" enum:"user,purchase,ticket"`
+	ID       string          `json:"id" doc:"id of the user, purchase or ticket"`
+	Rank     float64         `json:"rank"`
+	User     User            `json:"user" doc:"the user found or the buyer"`
+	Purchase *SearchPurchase `json:"purchase,omitempty"`
+	Ticket   string          `json:"ticket,omitempty"`
+}
+
+type SearchPurchase struct {
+	ID                string    `json:"id"`
+	Status            string    `json:"status"`
+	TransactionDigits string    `json:"transactionDigits"`
+	Quantity          int       `json:"quantity"`
+	CreatedAt         time.Time `json:"date"`
+}
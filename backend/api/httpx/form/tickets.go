@@ -1,5 +1,7 @@
 package form
 
+import "time"
+
 type tickets []string
 
 type SearchAvailableTickets struct {
@@ -7,9 +9,25 @@ type SearchAvailableTickets struct {
 }
 
 type TicketsPercentage struct {
+	// Percentage counts every ticket taken, sold or reserved, as it did
+	// before tickets were reserved
 	Percentage float64 `json:"vendidos"`
+	// Sold only counts tickets of verified purchases
+	Sold float64 `json:"verificados"`
+	// Reserved counts tickets of purchases waiting for verification
+	Reserved float64 `json:"reservados"`
 }
 
 type UserTickets struct {
 	Tickets tickets `json:"tickets"`
 }
+
+type TicketsUpdate struct {
+	TicketsPercentage
+	Taken    tickets `json:"taken,omitempty" doc:"numbers sold or reserved since the last update"`
+	Released tickets `json:"released,omitempty" doc:"numbers available again since the last update"`
+}
+
+type StreamPing struct {
+	Time time.Time `json:"time"`
+}
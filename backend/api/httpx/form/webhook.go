@@ -0,0 +1,37 @@
+package form
+
+import "time"
+
+type WebhookRequest struct {
+	URL         string   `json:"url" format:"uri" maxLength:"2048"`
+	Events      []string `json:"events" minItems:"1" uniqueItems:"true" enum:"purchase.created,purchase.verified,purchase.cancelled,purchase.status_changed,price.changed"`
+	Description string   `json:"description,omitempty" maxLength:"200"`
+	Active      bool     `json:"active" default:"true"`
+	Secret      string   `json:"secret,omitempty" minLength:"16" maxLength:"128" doc:"generated when left empty on creation, kept when left empty on update"`
+}
+
+type Webhook struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	Secret      string    `json:"secret,omitempty" doc:"only returned when created or changed"`
+	CreatedAt   time.Time `json:"date"`
+}
+
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	EndpointID     string     `json:"endpointId"`
+	EventID        string     `json:"eventId"`
+	Event          string     `json:"event"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus *int       `json:"responseStatus,omitempty"`
+	ResponseBody   *string    `json:"responseBody,omitempty"`
+	Error          *string    `json:"error,omitempty"`
+	NextAttemptAt  *time.Time `json:"nextAttemptAt,omitempty"`
+	LastAttemptAt  *time.Time `json:"lastAttemptAt,omitempty"`
+	Payload        any        `json:"payload"`
+	CreatedAt      time.Time  `json:"date"`
+}
@@ -0,0 +1,107 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/api/httpx/form"
+	mymiddlewares "rifa/backend/api/httpx/middlewares"
+	"rifa/backend/internal/core/lottery"
+	"rifa/backend/internal/types"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func RegisterLotteryRoutes(
+	api huma.API,
+	db database.DB,
+	opts config.ServiceOpts,
+) {
+	srv := lottery.NewService(db)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID:   "lottery",
+			Method:        http.MethodGet,
+			Path:          "/api/lottery",
+			Summary:       "Get the active lottery and its purchase limits",
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			_ *struct{},
+		) (*dto.LotteryOutput, error) {
+			active, err := srv.GetActive(ctx)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to get lottery",
+				)
+			}
+
+			return &dto.LotteryOutput{Body: toLotteryConfig(active)}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "updateLotteryLimits",
+			Method:      http.MethodPut,
+			Path:        "/api/admin/lottery/limits",
+			Summary:     "Set the purchase limits and expiry of the active lottery (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.UpdateLotteryLimitsInput,
+		) (*dto.LotteryOutput, error) {
+			updated, err := srv.UpdateLimits(
+				ctx,
+				actorFromContext(ctx),
+				types.Lottery{
+					MinPerPurchase:     input.Body.MinPerPurchase,
+					MaxPerPurchase:     input.Body.MaxPerPurchase,
+					MaxPerUser:         input.Body.MaxPerUser,
+					PendingExpiryHours: input.Body.PendingExpiryHours,
+				},
+			)
+			if errors.Is(err, lottery.ErrInvalidLimits) {
+				return nil, huma.Error422UnprocessableEntity(
+					"Maximums can't be lower than the minimum per purchase " +
+						"and the expiry must be at least an hour",
+				)
+			}
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to update lottery limits",
+				)
+			}
+
+			return &dto.LotteryOutput{Body: toLotteryConfig(updated)}, nil
+		},
+	)
+}
+
+func toLotteryConfig(l types.Lottery) form.LotteryConfig {
+	return form.LotteryConfig{
+		ID:   l.ID,
+		Name: l.Name,
+		LotteryLimits: form.LotteryLimits{
+			MinPerPurchase:     l.MinPerPurchase,
+			MaxPerPurchase:     l.MaxPerPurchase,
+			MaxPerUser:         l.MaxPerUser,
+			PendingExpiryHours: l.PendingExpiryHours,
+		},
+	}
+}
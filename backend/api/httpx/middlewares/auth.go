@@ -3,6 +3,7 @@ package mymiddlewares
 import (
 	"errors"
 	"log"
+	"net"
 	"net/http"
 
 	"rifa/backend/pkg/config"
@@ -88,6 +89,17 @@ func RequireAdminSession(
 			return
 		}
 		ctx = huma.WithValue(ctx, "claims", claims)
+		ctx = huma.WithValue(ctx, "remoteAddr", remoteIP(ctx.RemoteAddr()))
 		next(ctx)
 	}
 }
+
+// remoteIP drops the port of the client address, which is only there when
+// the request didn't come through a proxy setting the real IP
+func remoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,209 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/api/httpx/form"
+	mymiddlewares "rifa/backend/api/httpx/middlewares"
+	"rifa/backend/internal/core/paymentmethod"
+	"rifa/backend/internal/types"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func RegisterPaymentMethodRoutes(
+	api huma.API,
+	db database.DB,
+	opts config.ServiceOpts,
+) {
+	srv := paymentmethod.NewService(db)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID:   "paymentMethods",
+			Method:        http.MethodGet,
+			Path:          "/api/payment-methods",
+			Summary:       "List the enabled payment methods",
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			_ *struct{},
+		) (*dto.PaymentMethodsOutput, error) {
+			methods, err := srv.List(ctx, true)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to get payment methods",
+				)
+			}
+
+			return &dto.PaymentMethodsOutput{
+				Body: toPaymentMethodsResponse(methods),
+			}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "adminPaymentMethods",
+			Method:      http.MethodGet,
+			Path:        "/api/admin/payment-methods",
+			Summary:     "List every payment method (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			_ *struct{},
+		) (*dto.PaymentMethodsOutput, error) {
+			methods, err := srv.List(ctx, false)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to get payment methods",
+				)
+			}
+
+			return &dto.PaymentMethodsOutput{
+				Body: toPaymentMethodsResponse(methods),
+			}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "createPaymentMethod",
+			Method:      http.MethodPost,
+			Path:        "/api/admin/payment-methods",
+			Summary:     "Create a payment method (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusCreated,
+		},
+		func(
+			ctx context.Context,
+			input *dto.CreatePaymentMethodInput,
+		) (*struct{}, error) {
+			method := toPaymentMethod(
+				input.Body.Code,
+				input.Body.PaymentMethodRequest,
+			)
+			err := srv.Create(ctx, actorFromContext(ctx), method)
+			if err != nil {
+				log.Println(err)
+				return nil, paymentMethodError(err)
+			}
+
+			return nil, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "updatePaymentMethod",
+			Method:      http.MethodPut,
+			Path:        "/api/admin/payment-methods/{code}",
+			Summary:     "Update a payment method (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusNoContent,
+		},
+		func(
+			ctx context.Context,
+			input *dto.UpdatePaymentMethodInput,
+		) (*struct{}, error) {
+			method := toPaymentMethod(input.Code, input.Body)
+			err := srv.Update(ctx, actorFromContext(ctx), method)
+			if err != nil {
+				log.Println(err)
+				return nil, paymentMethodError(err)
+			}
+
+			return nil, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "deletePaymentMethod",
+			Method:      http.MethodDelete,
+			Path:        "/api/admin/payment-methods/{code}",
+			Summary:     "Delete a payment method (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusNoContent,
+		},
+		func(
+			ctx context.Context,
+			input *dto.DeletePaymentMethodInput,
+		) (*struct{}, error) {
+			err := srv.Delete(ctx, actorFromContext(ctx), input.Code)
+			if err != nil {
+				log.Println(err)
+				return nil, paymentMethodError(err)
+			}
+
+			return nil, nil
+		},
+	)
+}
+
+func paymentMethodError(err error) error {
+	switch {
+	case errors.Is(err, paymentmethod.ErrNotFound):
+		return huma.Error404NotFound("Payment method not found")
+	case errors.Is(err, paymentmethod.ErrAlreadyExists):
+		return huma.Error409Conflict("Payment method already exists")
+	case errors.Is(err, paymentmethod.ErrInvalidPattern):
+		return huma.Error422UnprocessableEntity("Invalid reference pattern")
+	}
+	return huma.Error500InternalServerError("Failed to save payment method")
+}
+
+func toPaymentMethod(
+	code string,
+	req form.PaymentMethodRequest,
+) types.PaymentMethod {
+	return types.PaymentMethod{
+		Code:             code,
+		DisplayName:      req.DisplayName,
+		Currency:         types.Currency(req.Currency),
+		AccountDetails:   req.AccountDetails,
+		Enabled:          req.Enabled,
+		ReferencePattern: req.ReferencePattern,
+	}
+}
+
+func toPaymentMethodsResponse(
+	methods []types.PaymentMethod,
+) []form.PaymentMethod {
+	res := make([]form.PaymentMethod, 0, len(methods))
+	for _, m := range methods {
+		res = append(res, form.PaymentMethod{
+			Code:             m.Code,
+			DisplayName:      m.DisplayName,
+			Currency:         string(m.Currency),
+			AccountDetails:   m.AccountDetails,
+			Enabled:          m.Enabled,
+			ReferencePattern: m.ReferencePattern,
+		})
+	}
+	return res
+}
@@ -2,21 +2,30 @@ package httpx
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"time"
 
 	"rifa/backend/api/httpx/dto"
 	"rifa/backend/api/httpx/form"
 	mymiddlewares "rifa/backend/api/httpx/middlewares"
 	"rifa/backend/internal/core/price"
+	"rifa/backend/internal/events"
+	"rifa/backend/internal/types"
 	"rifa/backend/pkg/config"
 	database "rifa/backend/pkg/db"
 
 	"github.com/danielgtaylor/huma/v2"
 )
 
-func RegisterPriceRoutes(api huma.API, db database.DB, opts config.ServiceOpts) {
-	srv := price.NewService(db)
+func RegisterPriceRoutes(
+	api huma.API,
+	db database.DB,
+	recorder events.Recorder,
+	opts config.ServiceOpts,
+) {
+	srv := price.NewService(db, recorder, opts)
 
 	huma.Register(
 		api,
@@ -31,7 +40,10 @@ func RegisterPriceRoutes(api huma.API, db database.DB, opts config.ServiceOpts)
 			ctx context.Context,
 			_ *struct{},
 		) (*dto.PriceOutput, error) {
-			prices, err := srv.GetPrices(ctx)
+			table, err := srv.GetPriceTable(ctx)
+			if errors.Is(err, price.ErrNoPrices) {
+				return nil, huma.Error404NotFound("No prices set yet")
+			}
 			if err != nil {
 				log.Println(err)
 				return nil, huma.Error500InternalServerError(
@@ -39,10 +51,21 @@ func RegisterPriceRoutes(api huma.API, db database.DB, opts config.ServiceOpts)
 				)
 			}
 
+			tiers := make([]form.PriceTier, 0, len(table.Tiers))
+			for _, tier := range table.Tiers {
+				tiers = append(tiers, form.PriceTier{
+					Quantity:     tier.Quantity,
+					PaidQuantity: tier.PaidQuantity,
+					BS:           tier.BsAmount,
+					USD:          tier.UsdAmount,
+				})
+			}
+
 			return &dto.PriceOutput{
 				Body: form.LotteryPrices{
-					BS:  prices.BsAmount,
-					USD: prices.UsdAmount,
+					BS:    table.BsAmount,
+					USD:   table.UsdAmount,
+					Tiers: tiers,
 				},
 			}, nil
 		},
@@ -64,7 +87,14 @@ func RegisterPriceRoutes(api huma.API, db database.DB, opts config.ServiceOpts)
 			ctx context.Context,
 			input *dto.PriceUpdateInput,
 		) (*struct{}, error) {
-			err := srv.Update(ctx, input.Body.BS, input.Body.USD)
+			prices := types.Prices{
+				BsAmount:  input.Body.BS,
+				UsdAmount: input.Body.USD,
+			}
+			if input.Body.EffectiveFrom != nil {
+				prices.EffectiveFrom = *input.Body.EffectiveFrom
+			}
+			err := srv.Update(ctx, actorFromContext(ctx), prices)
 			if err != nil {
 				log.Println(err)
 				return nil, huma.Error500InternalServerError(
@@ -75,4 +105,96 @@ func RegisterPriceRoutes(api huma.API, db database.DB, opts config.ServiceOpts)
 			return nil, nil
 		},
 	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "updatePriceTiers",
+			Method:      http.MethodPut,
+			Path:        "/api/prices/tiers",
+			Summary:     "Replace the bundle prices of the active lottery (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusNoContent,
+		},
+		func(
+			ctx context.Context,
+			input *dto.PriceTiersInput,
+		) (*struct{}, error) {
+			tiers := make([]types.PriceTier, 0, len(input.Body))
+			for _, tier := range input.Body {
+				tiers = append(tiers, types.PriceTier{
+					Quantity:     tier.Quantity,
+					PaidQuantity: tier.PaidQuantity,
+					BsAmount:     tier.BS,
+					UsdAmount:    tier.USD,
+				})
+			}
+
+			err := srv.UpdateTiers(ctx, actorFromContext(ctx), tiers)
+			if errors.Is(err, price.ErrInvalidTiers) {
+				return nil, huma.Error422UnprocessableEntity(
+					"Each bundle needs a unique quantity and either a " +
+						"paid quantity below it or both fixed amounts",
+				)
+			}
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to update price tiers",
+				)
+			}
+
+			return nil, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "priceHistory",
+			Method:      http.MethodGet,
+			Path:        "/api/prices/history",
+			Summary:     "List the price changes of the active lottery (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.GetPriceHistory,
+		) (*dto.PriceHistoryOutput, error) {
+			history, total, err := srv.GetHistory(
+				ctx,
+				input.Page,
+				input.ItemCount,
+			)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to get price history",
+				)
+			}
+
+			now := time.Now()
+			body := make([]form.PriceHistoryEntry, 0, len(history))
+			for _, change := range history {
+				entry := form.PriceHistoryEntry{
+					ID:            change.ID,
+					BS:            change.BsAmount,
+					USD:           change.UsdAmount,
+					EffectiveFrom: change.EffectiveFrom,
+					Scheduled:     change.EffectiveFrom.After(now),
+					ChangedByID:   change.CreatedBy,
+					ChangedBy:     change.CreatedByName,
+					CreatedAt:     change.CreatedAt,
+				}
+				body = append(body, entry)
+			}
+
+			return &dto.PriceHistoryOutput{Body: body, Total: total}, nil
+		},
+	)
 }
@@ -3,19 +3,27 @@ package httpx
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"rifa/backend/api/httpx/dto"
 	"rifa/backend/api/httpx/form"
 	mymiddlewares "rifa/backend/api/httpx/middlewares"
+	"rifa/backend/internal/core/coupon"
 	"rifa/backend/internal/core/email"
+	"rifa/backend/internal/core/price"
 	"rifa/backend/internal/core/purchase"
+	"rifa/backend/internal/events"
+	"rifa/backend/internal/types"
 	"rifa/backend/pkg/config"
 	database "rifa/backend/pkg/db"
-	"rifa/backend/pkg/utils"
+	"rifa/backend/pkg/spreadsheet"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/golang-jwt/jwt/v5"
@@ -26,6 +34,7 @@ const maxFileBytes int64 = 1 * 1024 * 1024
 func RegisterPurchaseRoutes(
 	api huma.API,
 	db database.DB,
+	recorder events.Recorder,
 	opts config.ServiceOpts,
 ) {
 	emailer := email.NewMailerooClient(
@@ -34,7 +43,7 @@ func RegisterPurchaseRoutes(
 		opts.Email.EmailReciever,
 		opts.Email.EmailURL,
 	)
-	srv := purchase.NewService(db, emailer)
+	srv := purchase.NewService(db, emailer, recorder, opts)
 
 	huma.Register(
 		api,
@@ -78,15 +87,20 @@ func RegisterPurchaseRoutes(
 				)
 			}
 
-			purchase := &form.CreatePurchaseRequest{
-				UserID:   claims["id"].(string),
-				Quantity: formData.Quantity,
-				MontoBs: utils.ParseFloatOrZero(
-					formData.MontoBs,
-				),
-				MontoUSD: utils.ParseFloatOrZero(
-					formData.MontoUSD,
-				),
+			montoBs, err := parseAmount(formData.MontoBs)
+			if err != nil {
+				return nil, huma.Error422UnprocessableEntity("Monto en Bs invalido")
+			}
+			montoUSD, err := parseAmount(formData.MontoUSD)
+			if err != nil {
+				return nil, huma.Error422UnprocessableEntity("Monto en USD invalido")
+			}
+
+			purchaseReq := &form.CreatePurchaseRequest{
+				UserID:            claims["id"].(string),
+				Quantity:          formData.Quantity,
+				MontoBs:           montoBs,
+				MontoUSD:          montoUSD,
 				PaymentMethod:     formData.PaymentMethod,
 				TransactionDigits: formData.TransactionDigits,
 				SelectedNumbers: func() []string {
@@ -97,9 +111,40 @@ func RegisterPurchaseRoutes(
 					return []string{}
 				}(),
 				PaymentScreenshot: screenshot,
+				CouponCode:        formData.Coupon,
 			}
-			if err := srv.Create(ctx, purchase); err != nil {
+			if err := srv.Create(ctx, purchaseReq); err != nil {
 				log.Println(err)
+				var limitErr *purchase.LimitError
+				if errors.As(err, &limitErr) {
+					return nil, purchaseLimitError(limitErr)
+				}
+				switch {
+				case errors.Is(err, purchase.ErrDuplicatePayment):
+					return nil, huma.Error409Conflict(
+						"La referencia de pago ya fue registrada",
+					)
+				case errors.Is(err, purchase.ErrPaymentMethod):
+					return nil, huma.Error422UnprocessableEntity(
+						"Metodo de pago no disponible",
+					)
+				case errors.Is(err, purchase.ErrInvalidReference):
+					return nil, huma.Error422UnprocessableEntity(
+						"Referencia de pago invalida",
+					)
+				case errors.Is(err, price.ErrNoPrices):
+					return nil, huma.Error409Conflict(
+						"Los precios aun no estan disponibles",
+					)
+				case errors.Is(err, purchase.ErrAmountMismatch):
+					return nil, huma.Error422UnprocessableEntity(
+						"El monto no corresponde al precio actual",
+					)
+				case errors.Is(err, coupon.ErrNotFound),
+					errors.Is(err, coupon.ErrUnavailable),
+					errors.Is(err, coupon.ErrExhausted):
+					return nil, couponError(err)
+				}
 				return nil, huma.Error500InternalServerError(
 					"Failed to save purchase",
 				)
@@ -124,18 +169,79 @@ func RegisterPurchaseRoutes(
 		ctx context.Context,
 		input *dto.GetAllPurchases,
 	) (*dto.PurchasesOutput, error) {
-		purchases, total, err := srv.GetAll(ctx, *input)
+		purchases, total, next, err := srv.GetAll(ctx, *input)
 		if err != nil {
 			log.Println(err)
+			if errors.Is(err, types.ErrInvalidCursor) {
+				return nil, huma.Error400BadRequest("Invalid cursor")
+			}
 			return nil, huma.Error500InternalServerError(
 				"Failed to get purchases",
 			)
 		}
 
-		output := dto.PurchasesOutput{Body: purchases, Total: total}
+		output := dto.PurchasesOutput{
+			Body:       purchases,
+			Total:      total,
+			NextCursor: next,
+		}
 		return &output, nil
 	})
 
+	huma.Register(api, huma.Operation{
+		OperationID: "exportPurchases",
+		Method:      http.MethodGet,
+		Path:        "/api/purchases/export",
+		Summary:     "Export purchases as a CSV or XLSX file (admin only)",
+		Middlewares: huma.Middlewares{
+			mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+		},
+		DefaultStatus: http.StatusOK,
+	}, func(
+		ctx context.Context,
+		input *dto.ExportPurchases,
+	) (*dto.ExportPurchasesOutput, error) {
+		format := spreadsheet.Format(input.Format)
+		filename := fmt.Sprintf(
+			"compras-%s.%s",
+			time.Now().Format("2006-01-02"),
+			format,
+		)
+
+		return &dto.ExportPurchasesOutput{
+			ContentType: format.ContentType(),
+			ContentDisposition: fmt.Sprintf(
+				"attachment; filename=%q",
+				filename,
+			),
+			Body: func(hctx huma.Context) {
+				// Large exports outlast the server write timeout
+				if rw, ok := hctx.BodyWriter().(http.ResponseWriter); ok {
+					rc := http.NewResponseController(rw)
+					if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+						log.Println(err)
+					}
+				}
+
+				// Headers are already sent once rows stream, so failures
+				// can only be logged and leave a truncated file
+				w, err := spreadsheet.NewWriter(hctx.BodyWriter(), format)
+				if err != nil {
+					log.Println(err)
+					return
+				}
+				err = srv.Export(hctx.Context(), input.PurchaseFilters, w)
+				if err != nil {
+					log.Println(err)
+					return
+				}
+				if err := w.Close(); err != nil {
+					log.Println(err)
+				}
+			},
+		}, nil
+	})
+
 	huma.Register(api, huma.Operation{
 		OperationID: "leaderboard",
 		Method:      http.MethodGet,
@@ -149,7 +255,7 @@ func RegisterPurchaseRoutes(
 		ctx context.Context,
 		input *dto.GetMostPurchases,
 	) (*dto.MostPurchasesOutput, error) {
-		leaderboard, err := srv.GetLeaderboard(ctx, *input)
+		leaderboard, total, err := srv.GetLeaderboard(ctx, *input)
 		if err != nil {
 			log.Println(err)
 			return nil, huma.Error500InternalServerError(
@@ -159,9 +265,31 @@ func RegisterPurchaseRoutes(
 
 		output := dto.MostPurchasesOutput{}
 		output.Body = leaderboard
+		output.Total = total
 		return &output, nil
 	})
 
+	huma.Register(api, huma.Operation{
+		OperationID:   "publicLeaderboard",
+		Method:        http.MethodGet,
+		Path:          "/api/leaderboard",
+		Summary:       "Top buyers of the active lottery with masked names",
+		DefaultStatus: http.StatusOK,
+	}, func(
+		ctx context.Context,
+		input *dto.GetPublicLeaderboard,
+	) (*dto.PublicLeaderboardOutput, error) {
+		leaders, err := srv.GetPublicLeaderboard(ctx, input.Limit)
+		if err != nil {
+			log.Println(err)
+			return nil, huma.Error500InternalServerError(
+				"Failed to get leaderboard",
+			)
+		}
+
+		return &dto.PublicLeaderboardOutput{Body: leaders}, nil
+	})
+
 	huma.Register(
 		api,
 		huma.Operation{
@@ -175,9 +303,22 @@ func RegisterPurchaseRoutes(
 			DefaultStatus: http.StatusNoContent,
 		},
 		func(ctx context.Context, input *dto.UpdatePurchase) (*struct{}, error) {
-			err := srv.UpdateStatus(ctx, input.ID, input.Body.Status)
+			err := srv.UpdateStatus(
+				ctx,
+				actorFromContext(ctx),
+				input.ID,
+				input.Body.Status,
+			)
 			if err != nil {
 				log.Println(err)
+				switch {
+				case errors.Is(err, purchase.ErrPurchaseNotFound):
+					return nil, huma.Error404NotFound("Purchase not found")
+				case errors.Is(err, purchase.ErrIllegalTransition):
+					return nil, huma.Error422UnprocessableEntity(
+						"Illegal status transition",
+					)
+				}
 				return nil, huma.Error500InternalServerError(
 					"Failed to update purchase",
 				)
@@ -187,6 +328,50 @@ func RegisterPurchaseRoutes(
 		},
 	)
 
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "bulkUpdatePurchases",
+			Method:      http.MethodPatch,
+			Path:        "/api/purchases/bulk",
+			Summary:     "Update the status of several purchases (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.BulkUpdatePurchases,
+		) (*dto.BulkUpdatePurchasesOutput, error) {
+			changes, err := srv.BulkUpdateStatus(
+				ctx,
+				actorFromContext(ctx),
+				input.Body.IDs,
+				types.PurchaseStatus(input.Body.Status),
+				input.Body.Reason,
+			)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to update purchases",
+				)
+			}
+
+			results := make([]form.StatusChangeResult, 0, len(changes))
+			for _, change := range changes {
+				results = append(results, form.StatusChangeResult{
+					ID:     change.PurchaseID,
+					Result: string(change.Result),
+				})
+			}
+
+			return &dto.BulkUpdatePurchasesOutput{
+				Body: form.BulkStatusResponse{Results: results},
+			}, nil
+		},
+	)
+
 	huma.Register(
 		api,
 		huma.Operation{
@@ -215,3 +400,35 @@ func RegisterPurchaseRoutes(
 		},
 	)
 }
+
+func purchaseLimitError(err *purchase.LimitError) error {
+	switch {
+	case errors.Is(err, purchase.ErrBelowMinimum):
+		return huma.Error422UnprocessableEntity(fmt.Sprintf(
+			"La cantidad minima de numeros por compra es %d",
+			err.Limit,
+		))
+	case errors.Is(err, purchase.ErrAboveMaximum):
+		return huma.Error422UnprocessableEntity(fmt.Sprintf(
+			"La cantidad maxima de numeros por compra es %d",
+			err.Limit,
+		))
+	}
+	return huma.Error422UnprocessableEntity(fmt.Sprintf(
+		"Alcanzaste el limite de numeros por persona, puedes comprar %d mas",
+		err.Limit,
+	))
+}
+
+// parseAmount parses an amount sent in the purchase form, which must be a
+// finite number not below zero
+func parseAmount(s string) (float64, error) {
+	amount, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(amount) || math.IsInf(amount, 0) || amount < 0 {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
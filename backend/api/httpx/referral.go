@@ -0,0 +1,86 @@
+package httpx
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"rifa/backend/api/httpx/dto"
+	mymiddlewares "rifa/backend/api/httpx/middlewares"
+	"rifa/backend/internal/core/referral"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func RegisterReferralRoutes(
+	api huma.API,
+	db database.DB,
+	opts config.ServiceOpts,
+) {
+	srv := referral.NewService(db, opts)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "myReferrals",
+			Method:      http.MethodGet,
+			Path:        "/api/referrals/me",
+			Summary:     "Get the referral code and rewards of the current user",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			_ *struct{},
+		) (*dto.ReferralSummaryOutput, error) {
+			claims, ok := ctx.Value("claims").(jwt.MapClaims)
+			if !ok {
+				log.Println("No session claims")
+				return nil, huma.Error401Unauthorized("No session claims")
+			}
+
+			summary, err := srv.GetSummary(ctx, claims["id"].(string))
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to get referrals",
+				)
+			}
+
+			return &dto.ReferralSummaryOutput{Body: summary}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "topReferrers",
+			Method:      http.MethodGet,
+			Path:        "/api/admin/referrals",
+			Summary:     "List the users who referred the most buyers (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.GetTopReferrers,
+		) (*dto.TopReferrersOutput, error) {
+			referrers, total, err := srv.GetTopReferrers(ctx, *input)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to get referrers",
+				)
+			}
+
+			return &dto.TopReferrersOutput{Body: referrers, Total: total}, nil
+		},
+	)
+}
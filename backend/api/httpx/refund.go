@@ -0,0 +1,203 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/api/httpx/form"
+	mymiddlewares "rifa/backend/api/httpx/middlewares"
+	"rifa/backend/internal/core/email"
+	"rifa/backend/internal/core/refund"
+	"rifa/backend/internal/events"
+	"rifa/backend/internal/types"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func RegisterRefundRoutes(
+	api huma.API,
+	db database.DB,
+	recorder events.Recorder,
+	opts config.ServiceOpts,
+) {
+	emailer := email.NewMailerooClient(
+		opts.Email.MailerooApiKey,
+		opts.Email.EmailSender,
+		opts.Email.EmailReciever,
+		opts.Email.EmailURL,
+	)
+	srv := refund.NewService(db, emailer, recorder, opts)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "createRefund",
+			Method:      http.MethodPost,
+			Path:        "/api/admin/purchases/{id}/refunds",
+			Summary:     "Start a refund for a purchase (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusCreated,
+		},
+		func(
+			ctx context.Context,
+			input *dto.CreateRefundInput,
+		) (*dto.RefundOutput, error) {
+			created, err := srv.Create(
+				ctx,
+				actorFromContext(ctx),
+				types.Refund{
+					PurchaseID: input.PurchaseID,
+					Amount:     input.Body.Amount,
+					Currency:   types.Currency(input.Body.Currency),
+					Method:     input.Body.Method,
+					Reason:     input.Body.Reason,
+					Full:       input.Body.Full,
+				},
+			)
+			if err != nil {
+				log.Println(err)
+				return nil, refundError(err)
+			}
+
+			return &dto.RefundOutput{Body: toRefundResponse(created)}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "completeRefund",
+			Method:      http.MethodPost,
+			Path:        "/api/admin/refunds/{id}/complete",
+			Summary:     "Record the money of a refund as sent back (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.CompleteRefundInput,
+		) (*dto.RefundOutput, error) {
+			formData := input.RawBody.Data()
+
+			var proof []byte
+			if formData.Proof.IsSet {
+				if formData.Proof.Size > maxFileBytes {
+					return nil, huma.NewError(
+						http.StatusRequestEntityTooLarge,
+						http.StatusText(http.StatusRequestEntityTooLarge),
+						errors.New("image exceeds 1MB limit"),
+					)
+				}
+
+				r := io.LimitReader(formData.Proof, maxFileBytes+1)
+				var err error
+				proof, err = io.ReadAll(r)
+				if err != nil {
+					log.Println(err)
+					return nil, huma.Error400BadRequest(
+						"Could not read uploaded file",
+					)
+				}
+			}
+
+			completed, err := srv.Complete(
+				ctx,
+				actorFromContext(ctx),
+				input.ID,
+				formData.Reference,
+				proof,
+			)
+			if err != nil {
+				log.Println(err)
+				return nil, refundError(err)
+			}
+
+			return &dto.RefundOutput{Body: toRefundResponse(completed)}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "listRefunds",
+			Method:      http.MethodGet,
+			Path:        "/api/admin/refunds",
+			Summary:     "List refunds (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.GetRefunds,
+		) (*dto.RefundsOutput, error) {
+			refunds, total, err := srv.List(ctx, *input)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to get refunds",
+				)
+			}
+
+			body := make([]form.Refund, 0, len(refunds))
+			for _, r := range refunds {
+				body = append(body, toRefundResponse(r))
+			}
+			return &dto.RefundsOutput{Body: body, Total: total}, nil
+		},
+	)
+}
+
+func refundError(err error) error {
+	switch {
+	case errors.Is(err, refund.ErrNotFound):
+		return huma.Error404NotFound("Refund not found")
+	case errors.Is(err, refund.ErrPurchaseNotFound):
+		return huma.Error404NotFound("Purchase not found")
+	case errors.Is(err, refund.ErrAlreadyCompleted):
+		return huma.Error409Conflict("Refund already completed")
+	case errors.Is(err, refund.ErrNotRefundable):
+		return huma.Error422UnprocessableEntity(
+			"Only verified or cancelled purchases can be fully refunded",
+		)
+	case errors.Is(err, refund.ErrCurrency):
+		return huma.Error422UnprocessableEntity(
+			"Refunds must be made in the currency of the payment",
+		)
+	case errors.Is(err, refund.ErrExceedsPayment):
+		return huma.Error422UnprocessableEntity(
+			"Refunds can't exceed the amount paid",
+		)
+	}
+	return huma.Error500InternalServerError("Failed to process refund")
+}
+
+func toRefundResponse(r types.Refund) form.Refund {
+	return form.Refund{
+		ID:          r.ID,
+		PurchaseID:  r.PurchaseID,
+		Amount:      r.Amount,
+		Currency:    string(r.Currency),
+		Method:      r.Method,
+		Reference:   r.Reference,
+		Reason:      r.Reason,
+		Full:        r.Full,
+		Status:      string(r.Status),
+		Proof:       r.Proof,
+		CreatedBy:   r.CreatedBy,
+		CompletedBy: r.CompletedBy,
+		CreatedAt:   r.CreatedAt,
+		CompletedAt: r.CompletedAt,
+	}
+}
@@ -0,0 +1,79 @@
+package httpx
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"rifa/backend/api/httpx/dto"
+	mymiddlewares "rifa/backend/api/httpx/middlewares"
+	"rifa/backend/internal/core/reports"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func RegisterReportRoutes(
+	api huma.API,
+	db database.DB,
+	opts config.ServiceOpts,
+) {
+	srv := reports.NewService(db)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "revenueReport",
+			Method:      http.MethodGet,
+			Path:        "/api/reports/revenue",
+			Summary:     "Revenue of a lottery by period, payment method and status (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.GetRevenueReport,
+		) (*dto.RevenueReportOutput, error) {
+			report, err := srv.GetRevenue(ctx, *input)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to get revenue report",
+				)
+			}
+
+			return &dto.RevenueReportOutput{Body: report}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "salesVelocity",
+			Method:      http.MethodGet,
+			Path:        "/api/reports/sales-velocity",
+			Summary:     "Tickets sold per hour or day and projected sell out of the active lottery (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.GetSalesVelocity,
+		) (*dto.SalesVelocityOutput, error) {
+			velocity, err := srv.GetSalesVelocity(ctx, *input)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to get sales velocity",
+				)
+			}
+
+			return &dto.SalesVelocityOutput{Body: velocity}, nil
+		},
+	)
+}
@@ -0,0 +1,51 @@
+package httpx
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"rifa/backend/api/httpx/dto"
+	mymiddlewares "rifa/backend/api/httpx/middlewares"
+	"rifa/backend/internal/core/search"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func RegisterSearchRoutes(
+	api huma.API,
+	db database.DB,
+	opts config.ServiceOpts,
+) {
+	srv := search.NewService(db)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "adminSearch",
+			Method:      http.MethodGet,
+			Path:        "/api/admin/search",
+			Summary:     "Search users, purchases and tickets (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.AdminSearch,
+		) (*dto.AdminSearchOutput, error) {
+			hits, err := srv.Search(ctx, input.Query, input.Limit)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to search",
+				)
+			}
+
+			return &dto.AdminSearchOutput{Body: hits}, nil
+		},
+	)
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"rifa/backend/api/httpx/dto"
 	"rifa/backend/api/httpx/form"
@@ -15,10 +16,21 @@ import (
 	"rifa/backend/pkg/utils"
 
 	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/sse"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func RegisterTicketsRoutes(api huma.API, db database.DB, opts config.ServiceOpts) {
+// streamPingInterval is how often idle streams get a ping event
+const streamPingInterval = 30 * time.Second
+
+// RegisterTicketsRoutes serves the ticket availability from broadcaster,
+// which is run along with the other background jobs of the server
+func RegisterTicketsRoutes(
+	api huma.API,
+	db database.DB,
+	broadcaster *ticket.Broadcaster,
+	opts config.ServiceOpts,
+) {
 	srv := ticket.NewService(db)
 
 	huma.Register(
@@ -27,14 +39,14 @@ func RegisterTicketsRoutes(api huma.API, db database.DB, opts config.ServiceOpts
 			OperationID:   "ticketsPercentage",
 			Method:        http.MethodGet,
 			Path:          "/api/tickets/percentage",
-			Summary:       "percentage of tickets sold",
+			Summary:       "percentage of tickets sold and reserved",
 			DefaultStatus: http.StatusOK,
 		},
 		func(
 			ctx context.Context,
 			_ *struct{},
 		) (*dto.PercentageOfTicketsSoldOutput, error) {
-			percentage, err := srv.GetAvailability(ctx)
+			percentage, err := broadcaster.Snapshot(ctx)
 			if err != nil {
 				log.Println(err)
 				return nil, huma.Error500InternalServerError(
@@ -42,9 +54,52 @@ func RegisterTicketsRoutes(api huma.API, db database.DB, opts config.ServiceOpts
 				)
 			}
 
-			return &dto.PercentageOfTicketsSoldOutput{
-				Body: form.TicketsPercentage{Percentage: percentage},
-			}, nil
+			return &dto.PercentageOfTicketsSoldOutput{Body: percentage}, nil
+		},
+	)
+
+	sse.Register(
+		api,
+		huma.Operation{
+			OperationID: "ticketsStream",
+			Method:      http.MethodGet,
+			Path:        "/api/tickets/stream",
+			Summary:     "Stream the percentage of tickets sold and the numbers taken",
+		},
+		map[string]any{
+			"tickets": form.TicketsUpdate{},
+			"ping":    form.StreamPing{},
+		},
+		func(ctx context.Context, _ *struct{}, send sse.Sender) {
+			updates, unsubscribe := broadcaster.Subscribe()
+			defer unsubscribe()
+
+			percentage, err := broadcaster.Snapshot(ctx)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			err = send.Data(form.TicketsUpdate{TicketsPercentage: percentage})
+			if err != nil {
+				return
+			}
+
+			// Pings keep proxies from closing idle connections
+			ping := time.NewTicker(streamPingInterval)
+			defer ping.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case update := <-updates:
+					err = send.Data(update)
+				case now := <-ping.C:
+					err = send.Data(form.StreamPing{Time: now})
+				}
+				if err != nil {
+					return
+				}
+			}
 		},
 	)
 
@@ -0,0 +1,263 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/api/httpx/form"
+	mymiddlewares "rifa/backend/api/httpx/middlewares"
+	"rifa/backend/internal/core/webhook"
+	"rifa/backend/internal/types"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func RegisterWebhookRoutes(
+	api huma.API,
+	db database.DB,
+	opts config.ServiceOpts,
+) {
+	srv := webhook.NewService(db, opts)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "listWebhooks",
+			Method:      http.MethodGet,
+			Path:        "/api/admin/webhooks",
+			Summary:     "List the webhook endpoints (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			_ *struct{},
+		) (*dto.WebhooksOutput, error) {
+			endpoints, err := srv.List(ctx)
+			if err != nil {
+				log.Println(err)
+				return nil, huma.Error500InternalServerError(
+					"Failed to get webhooks",
+				)
+			}
+
+			body := make([]form.Webhook, 0, len(endpoints))
+			for _, e := range endpoints {
+				body = append(body, toWebhookForm(e, false))
+			}
+			return &dto.WebhooksOutput{Body: body}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "createWebhook",
+			Method:      http.MethodPost,
+			Path:        "/api/admin/webhooks",
+			Summary:     "Create a webhook endpoint, answering with its signing secret (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusCreated,
+		},
+		func(
+			ctx context.Context,
+			input *dto.CreateWebhookInput,
+		) (*dto.WebhookOutput, error) {
+			endpoint, err := srv.Create(
+				ctx,
+				actorFromContext(ctx),
+				toWebhookEndpoint("", input.Body),
+			)
+			if err != nil {
+				log.Println(err)
+				return nil, webhookError(err)
+			}
+
+			return &dto.WebhookOutput{Body: toWebhookForm(endpoint, true)}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "updateWebhook",
+			Method:      http.MethodPut,
+			Path:        "/api/admin/webhooks/{id}",
+			Summary:     "Update a webhook endpoint (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.UpdateWebhookInput,
+		) (*dto.WebhookOutput, error) {
+			endpoint, err := srv.Update(
+				ctx,
+				actorFromContext(ctx),
+				toWebhookEndpoint(input.ID, input.Body),
+			)
+			if err != nil {
+				log.Println(err)
+				return nil, webhookError(err)
+			}
+
+			return &dto.WebhookOutput{
+				Body: toWebhookForm(endpoint, input.Body.Secret != ""),
+			}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "deleteWebhook",
+			Method:      http.MethodDelete,
+			Path:        "/api/admin/webhooks/{id}",
+			Summary:     "Delete a webhook endpoint and its delivery log (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusNoContent,
+		},
+		func(
+			ctx context.Context,
+			input *dto.WebhookIDInput,
+		) (*struct{}, error) {
+			err := srv.Delete(ctx, actorFromContext(ctx), input.ID)
+			if err != nil {
+				log.Println(err)
+				return nil, webhookError(err)
+			}
+
+			return nil, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "listWebhookDeliveries",
+			Method:      http.MethodGet,
+			Path:        "/api/admin/webhooks/{id}/deliveries",
+			Summary:     "List the deliveries to a webhook endpoint, newest first (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.GetWebhookDeliveries,
+		) (*dto.WebhookDeliveriesOutput, error) {
+			deliveries, total, err := srv.ListDeliveries(ctx, *input)
+			if err != nil {
+				log.Println(err)
+				return nil, webhookError(err)
+			}
+
+			body := make([]form.WebhookDelivery, 0, len(deliveries))
+			for _, d := range deliveries {
+				body = append(body, toWebhookDeliveryForm(d))
+			}
+			return &dto.WebhookDeliveriesOutput{Body: body, Total: total}, nil
+		},
+	)
+
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "testWebhook",
+			Method:      http.MethodPost,
+			Path:        "/api/admin/webhooks/{id}/test",
+			Summary:     "Send a test event to a webhook endpoint and return the delivery (admin only)",
+			Middlewares: huma.Middlewares{
+				mymiddlewares.RequireAdminSession(api, opts.JwtOpts),
+			},
+			DefaultStatus: http.StatusOK,
+		},
+		func(
+			ctx context.Context,
+			input *dto.WebhookIDInput,
+		) (*dto.WebhookDeliveryOutput, error) {
+			delivery, err := srv.SendTest(ctx, input.ID)
+			if err != nil {
+				log.Println(err)
+				return nil, webhookError(err)
+			}
+
+			return &dto.WebhookDeliveryOutput{
+				Body: toWebhookDeliveryForm(delivery),
+			}, nil
+		},
+	)
+}
+
+func webhookError(err error) error {
+	switch {
+	case errors.Is(err, webhook.ErrNotFound):
+		return huma.Error404NotFound("Webhook not found")
+	case errors.Is(err, webhook.ErrInvalid):
+		return huma.Error422UnprocessableEntity(
+			"The URL must be http or https and every event must be known",
+		)
+	}
+	return huma.Error500InternalServerError("Failed to process webhook")
+}
+
+func toWebhookEndpoint(id string, req form.WebhookRequest) types.WebhookEndpoint {
+	return types.WebhookEndpoint{
+		ID:          id,
+		URL:         req.URL,
+		Secret:      req.Secret,
+		Events:      req.Events,
+		Description: req.Description,
+		Active:      req.Active,
+	}
+}
+
+// toWebhookForm shows the endpoint secret only when withSecret, right after
+// it was set
+func toWebhookForm(e types.WebhookEndpoint, withSecret bool) form.Webhook {
+	w := form.Webhook{
+		ID:          e.ID,
+		URL:         e.URL,
+		Events:      e.Events,
+		Description: e.Description,
+		Active:      e.Active,
+		CreatedAt:   e.CreatedAt,
+	}
+	if withSecret {
+		w.Secret = e.Secret
+	}
+	return w
+}
+
+func toWebhookDeliveryForm(d types.WebhookDelivery) form.WebhookDelivery {
+	return form.WebhookDelivery{
+		ID:             d.ID,
+		EndpointID:     d.EndpointID,
+		EventID:        d.EventID,
+		Event:          d.Event,
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		ResponseBody:   d.ResponseBody,
+		Error:          d.Error,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastAttemptAt:  d.LastAttemptAt,
+		Payload:        json.RawMessage(d.Payload),
+		CreatedAt:      d.CreatedAt,
+	}
+}
@@ -2,15 +2,35 @@ package api
 
 import (
 	"rifa/backend/api/httpx"
+	ticket "rifa/backend/internal/core/tickets"
+	"rifa/backend/internal/events"
 	"rifa/backend/pkg/config"
 	"rifa/backend/pkg/db"
 
 	"github.com/danielgtaylor/huma/v2"
 )
 
-func RegisterHttpRoutes(api huma.API, db db.DB, serviceOpts config.ServiceOpts) {
+func RegisterHttpRoutes(
+	api huma.API,
+	db db.DB,
+	bus *events.Bus,
+	outbox events.Recorder,
+	broadcaster *ticket.Broadcaster,
+	serviceOpts config.ServiceOpts,
+) {
 	httpx.RegisterAuthRoutes(api, db, serviceOpts)
-	httpx.RegisterPurchaseRoutes(api, db, serviceOpts)
-	httpx.RegisterTicketsRoutes(api, db, serviceOpts)
-	httpx.RegisterPriceRoutes(api, db, serviceOpts)
+	httpx.RegisterPurchaseRoutes(api, db, outbox, serviceOpts)
+	httpx.RegisterTicketsRoutes(api, db, broadcaster, serviceOpts)
+	httpx.RegisterPriceRoutes(api, db, outbox, serviceOpts)
+	httpx.RegisterAuditRoutes(api, db, serviceOpts)
+	httpx.RegisterPaymentMethodRoutes(api, db, serviceOpts)
+	httpx.RegisterExchangeRoutes(api, db, outbox, serviceOpts)
+	httpx.RegisterCouponRoutes(api, db, serviceOpts)
+	httpx.RegisterReferralRoutes(api, db, serviceOpts)
+	httpx.RegisterLotteryRoutes(api, db, serviceOpts)
+	httpx.RegisterRefundRoutes(api, db, outbox, serviceOpts)
+	httpx.RegisterReportRoutes(api, db, serviceOpts)
+	httpx.RegisterSearchRoutes(api, db, serviceOpts)
+	httpx.RegisterWebhookRoutes(api, db, serviceOpts)
+	httpx.RegisterEventRoutes(api, bus, serviceOpts)
 }
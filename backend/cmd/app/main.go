@@ -52,6 +52,9 @@ func main() {
 		}
 	}()
 
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
 	front := http.FS(dist)
 	server, err := core.NewHttpServer(
 		dbAdapter,
@@ -60,6 +63,7 @@ func main() {
 			Logger:      logger,
 			ServerOpts:  cfg.Server,
 			ServiceOpts: cfg.Service,
+			Context:     jobCtx,
 		},
 	)
 	if err != nil {
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"context"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/api/httpx/form"
+	"rifa/backend/internal/repository"
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+type Service interface {
+	// Record writes the entry in tx, so it's only kept when the change it
+	// describes is committed
+	Record(ctx context.Context, tx database.Tx, entry types.AuditEntry) error
+	// Hook returns a repository hook recording the entry returned by entry.
+	// It's called inside the transaction, once the ids the change generates
+	// are known.
+	Hook(entry func() types.AuditEntry) repository.TxHook
+	List(
+		ctx context.Context,
+		filters dto.GetAuditLog,
+	) ([]form.AuditEntry, int, error)
+}
+
+type service struct {
+	repo repository.AuditRepository
+}
+
+func NewService(db database.DB) Service {
+	return &service{
+		repo: repository.NewAuditRepository(db),
+	}
+}
+
+func (s *service) Record(
+	ctx context.Context,
+	tx database.Tx,
+	entry types.AuditEntry,
+) error {
+	return s.repo.Create(ctx, tx, entry)
+}
+
+// Hook returns the repository hook recording the entry built by entry when
+// the transaction runs, so it can include what the change generated
+func (s *service) Hook(entry func() types.AuditEntry) repository.TxHook {
+	return func(ctx context.Context, tx database.Tx) error {
+		return s.Record(ctx, tx, entry())
+	}
+}
+
+func (s *service) List(
+	ctx context.Context,
+	filters dto.GetAuditLog,
+) ([]form.AuditEntry, int, error) {
+	return s.repo.List(ctx, filters)
+}
@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+type fakeTx struct {
+	args [][]any
+}
+
+func (f *fakeTx) Query(context.Context, string, ...any) (database.Rows, error) {
+	return nil, nil
+}
+func (f *fakeTx) QueryRow(context.Context, string, ...any) database.Row { return nil }
+func (f *fakeTx) ExecContext(_ context.Context, _ string, args ...any) error {
+	f.args = append(f.args, args)
+	return nil
+}
+func (f *fakeTx) Commit(context.Context) error   { return nil }
+func (f *fakeTx) Rollback(context.Context) error { return nil }
+
+// The entry of a hook is built when the transaction runs it, so it can carry
+// the id the change generated
+func TestHook_BuildsEntryInTx(t *testing.T) {
+	srv := NewService(nil)
+	var id string
+	hook := srv.Hook(func() types.AuditEntry {
+		return types.AuditEntry{
+			Action:     types.AuditRefundCreated,
+			TargetType: types.AuditTargetRefund,
+			TargetID:   id,
+		}
+	})
+
+	id = "0199f1c2-generated"
+	tx := &fakeTx{}
+	if err := hook(context.Background(), tx); err != nil {
+		t.Fatalf("hook() error = %v", err)
+	}
+
+	if len(tx.args) != 1 {
+		t.Fatalf("writes = %d, want 1", len(tx.args))
+	}
+	if got := tx.args[0][3]; got != id {
+		t.Errorf("target id = %v, want %v", got, id)
+	}
+	if got := tx.args[0][1]; got != types.AuditRefundCreated {
+		t.Errorf("action = %v, want %v", got, types.AuditRefundCreated)
+	}
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 
 	"rifa/backend/api/httpx/form"
@@ -12,20 +13,27 @@ import (
 	"rifa/backend/pkg/utils"
 )
 
+// referralCodeLength keeps codes short enough to share by hand
+const referralCodeLength = 8
+
+var ErrInvalidReferralCode = errors.New("invalid referral code")
+
 type Service interface {
 	Register(ctx context.Context, input *form.RegisterRequest) error
 	Login(ctx context.Context, input *form.LoginRequest) (types.AuthUser, error)
 }
 
 type service struct {
-	users  repository.UserRepository
-	config config.ServiceOpts
+	users     repository.UserRepository
+	referrals repository.ReferralRepository
+	config    config.ServiceOpts
 }
 
 func NewAuthService(db database.DB, opts config.ServiceOpts) Service {
 	return &service{
-		users:  repository.NewUserRepository(db),
-		config: opts,
+		users:     repository.NewUserRepository(db),
+		referrals: repository.NewReferralRepository(db),
+		config:    opts,
 	}
 }
 
@@ -33,17 +41,36 @@ func (s *service) Register(
 	ctx context.Context,
 	input *form.RegisterRequest,
 ) error {
+	var referredBy *string
+	if input.ReferralCode != "" {
+		referrerID, err := s.referrals.GetUserIDByCode(ctx, input.ReferralCode)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidReferralCode
+		}
+		if err != nil {
+			return err
+		}
+		referredBy = &referrerID
+	}
+
 	hashed, err := utils.HashPassword(input.Password)
 	if err != nil {
 		return err
 	}
 
+	referralCode, err := utils.RandomCode(referralCodeLength)
+	if err != nil {
+		return err
+	}
+
 	user := &types.User{
-		Name:     input.Name,
-		Email:    input.Email,
-		Phone:    input.Phone,
-		Password: string(hashed),
-		Role:     types.CustomerRole,
+		Name:         input.Name,
+		Email:        input.Email,
+		Phone:        input.Phone,
+		Password:     string(hashed),
+		Role:         types.CustomerRole,
+		ReferralCode: referralCode,
+		ReferredBy:   referredBy,
 	}
 
 	err = s.users.CreateUser(ctx, user)
@@ -0,0 +1,274 @@
+package coupon
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"rifa/backend/internal/core/audit"
+	"rifa/backend/internal/core/price"
+	"rifa/backend/internal/events"
+	"rifa/backend/internal/repository"
+	"rifa/backend/internal/types"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+)
+
+var (
+	ErrNotFound      = errors.New("coupon not found")
+	ErrAlreadyExists = errors.New("coupon already exists")
+	ErrInvalid       = errors.New("invalid coupon")
+	ErrUnavailable   = errors.New("coupon not available")
+	ErrExhausted     = errors.New("coupon usage limit reached")
+)
+
+// Quote is what a purchase of Quantity tickets costs with the coupon applied
+type Quote struct {
+	Coupon       types.Coupon
+	Quantity     int
+	BonusTickets int
+	BsDiscount   float64
+	UsdDiscount  float64
+	BsTotal      float64
+	UsdTotal     float64
+}
+
+type Service interface {
+	List(ctx context.Context) ([]types.Coupon, error)
+	Create(ctx context.Context, actor types.Actor, coupon types.Coupon) error
+	Update(ctx context.Context, actor types.Actor, coupon types.Coupon) error
+	// Check returns the coupon if userID can use it now in the active
+	// lottery, or the reason they can't
+	Check(ctx context.Context, code, userID string) (types.Coupon, error)
+	Quote(
+		ctx context.Context,
+		code,
+		userID string,
+		quantity int,
+	) (Quote, error)
+	// Redeem records the use of a checked coupon by the purchase within tx,
+	// failing with ErrExhausted if its limits were reached in the meantime
+	Redeem(
+		ctx context.Context,
+		tx database.Tx,
+		coupon types.Coupon,
+		userID,
+		purchaseID string,
+	) error
+}
+
+type service struct {
+	repo       repository.CouponRepository
+	ticketRepo repository.TicketRepository
+	prices     price.Service
+	audit      audit.Service
+}
+
+func NewService(db database.DB, opts config.ServiceOpts) Service {
+	return &service{
+		repo:       repository.NewCouponRepository(db),
+		ticketRepo: repository.NewTicketRepository(db),
+		prices:     price.NewService(db, events.Discard, opts),
+		audit:      audit.NewService(db),
+	}
+}
+
+func (s *service) List(ctx context.Context) ([]types.Coupon, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *service) Create(
+	ctx context.Context,
+	actor types.Actor,
+	coupon types.Coupon,
+) error {
+	coupon.Code = strings.ToUpper(strings.TrimSpace(coupon.Code))
+	if coupon.Code == "" || !validDefinition(coupon) {
+		return ErrInvalid
+	}
+
+	_, err := s.get(ctx, coupon.Code)
+	if err == nil {
+		return ErrAlreadyExists
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	err = s.repo.Create(
+		ctx,
+		&coupon,
+		s.audited(actor, types.AuditCouponCreated, coupon.Code, nil, &coupon),
+	)
+	// A concurrent create of the same code gets past the check above and is
+	// refused by the unique index
+	if database.IsUniqueViolation(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+func (s *service) Update(
+	ctx context.Context,
+	actor types.Actor,
+	coupon types.Coupon,
+) error {
+	if !validDefinition(coupon) {
+		return ErrInvalid
+	}
+
+	before, err := s.get(ctx, coupon.Code)
+	if err != nil {
+		return err
+	}
+	coupon.ID = before.ID
+	coupon.Code = before.Code
+
+	return s.repo.Update(
+		ctx,
+		coupon,
+		s.audited(actor, types.AuditCouponUpdated, coupon.Code, before, coupon),
+	)
+}
+
+func (s *service) Check(
+	ctx context.Context,
+	code,
+	userID string,
+) (types.Coupon, error) {
+	coupon, err := s.get(ctx, strings.TrimSpace(code))
+	if err != nil {
+		return types.Coupon{}, err
+	}
+
+	lotteryID, err := s.ticketRepo.GetActiveLotteryID(ctx)
+	if err != nil {
+		return types.Coupon{}, err
+	}
+	if !coupon.ValidAt(time.Now(), lotteryID) {
+		return types.Coupon{}, ErrUnavailable
+	}
+
+	if coupon.MaxUses != nil && coupon.Uses >= *coupon.MaxUses {
+		return types.Coupon{}, ErrExhausted
+	}
+	if coupon.PerUserLimit != nil {
+		used, err := s.repo.CountUserRedemptions(ctx, coupon.ID, userID)
+		if err != nil {
+			return types.Coupon{}, err
+		}
+		if used >= *coupon.PerUserLimit {
+			return types.Coupon{}, ErrExhausted
+		}
+	}
+
+	return coupon, nil
+}
+
+func (s *service) Quote(
+	ctx context.Context,
+	code,
+	userID string,
+	quantity int,
+) (Quote, error) {
+	coupon, err := s.Check(ctx, code, userID)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	table, err := s.prices.GetPriceTable(ctx)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	quote := Quote{
+		Coupon:       coupon,
+		Quantity:     quantity,
+		BonusTickets: coupon.BonusTickets(),
+		BsTotal:      table.Total(quantity, types.CurrencyBs),
+		UsdTotal:     table.Total(quantity, types.CurrencyUSD),
+	}
+	quote.BsDiscount = coupon.Discount(
+		quote.BsTotal,
+		types.CurrencyBs,
+		table.Prices,
+	)
+	quote.UsdDiscount = coupon.Discount(
+		quote.UsdTotal,
+		types.CurrencyUSD,
+		table.Prices,
+	)
+	quote.BsTotal -= quote.BsDiscount
+	quote.UsdTotal -= quote.UsdDiscount
+
+	return quote, nil
+}
+
+func (s *service) Redeem(
+	ctx context.Context,
+	tx database.Tx,
+	coupon types.Coupon,
+	userID,
+	purchaseID string,
+) error {
+	redeemed, err := s.repo.Redeem(ctx, tx, coupon.ID, userID, purchaseID)
+	if err != nil {
+		return err
+	}
+	if !redeemed {
+		return ErrExhausted
+	}
+	return nil
+}
+
+func (s *service) get(ctx context.Context, code string) (types.Coupon, error) {
+	coupon, err := s.repo.GetByCode(ctx, code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.Coupon{}, ErrNotFound
+	}
+	return coupon, err
+}
+
+// audited returns the hook recording a coupon change in its transaction
+func (s *service) audited(
+	actor types.Actor,
+	action types.AuditAction,
+	code string,
+	before,
+	after any,
+) repository.TxHook {
+	return s.audit.Hook(func() types.AuditEntry {
+		return types.AuditEntry{
+			Actor:      actor,
+			Action:     action,
+			TargetType: types.AuditTargetCoupon,
+			TargetID:   code,
+			Before:     before,
+			After:      after,
+		}
+	})
+}
+
+func validDefinition(coupon types.Coupon) bool {
+	switch coupon.Type {
+	case types.CouponPercent:
+		if coupon.Value > 100 {
+			return false
+		}
+	case types.CouponBonusTickets:
+		if coupon.Value != float64(int(coupon.Value)) {
+			return false
+		}
+	case types.CouponFixed:
+	default:
+		return false
+	}
+
+	if coupon.StartsAt != nil && coupon.EndsAt != nil &&
+		!coupon.StartsAt.Before(*coupon.EndsAt) {
+		return false
+	}
+	return coupon.Value > 0
+}
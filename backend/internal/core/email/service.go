@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"html"
 	"log"
 	"net/http"
 	"time"
@@ -17,6 +18,16 @@ import (
 // Mailer defines the contract for sending emails.
 type Mailer interface {
 	SendPurchaseConfirmation(purchase types.Purchase) error
+	SendPurchaseStatusUpdate(
+		to string,
+		purchase types.Purchase,
+		reason string,
+	) error
+	SendPurchaseExpiryWarning(
+		to string,
+		purchase types.Purchase,
+		deadline time.Time,
+	) error
 }
 
 type mailerooClient struct {
@@ -67,6 +78,64 @@ func (m *mailerooClient) SendPurchaseConfirmation(purchase types.Purchase) error
 		}},
 	}
 
+	return m.send(payload)
+}
+
+// SendPurchaseStatusUpdate lets the buyer know their purchase was verified,
+// cancelled, refunded or moved back to pending.
+func (m *mailerooClient) SendPurchaseStatusUpdate(
+	to string,
+	purchase types.Purchase,
+	reason string,
+) error {
+	if reason == "" {
+		reason = "-"
+	}
+	htmlBody := fmt.Sprintf(
+		StatusUpdateHTMLTemplate,
+		statusLabel(purchase.Status),
+		purchase.CreatedAt.Format("02 Jan 2006 15:04"),
+		purchase.Quantity,
+		purchase.PaymentMethod,
+		purchase.TransactionDigits,
+		html.EscapeString(reason),
+	)
+	payload := EmailPayload{
+		FromEmail: EmailObject{Address: m.from, DisplayName: "Compras"},
+		ToEmail:   []EmailObject{{Address: to}},
+		Subject:   "Tu compra fue " + statusLabel(purchase.Status),
+		HtmlBody:  htmlBody,
+	}
+
+	return m.send(payload)
+}
+
+// SendPurchaseExpiryWarning reminds the buyer that their pending purchase will
+// be cancelled if it isn't verified by the deadline.
+func (m *mailerooClient) SendPurchaseExpiryWarning(
+	to string,
+	purchase types.Purchase,
+	deadline time.Time,
+) error {
+	htmlBody := fmt.Sprintf(
+		ExpiryWarningHTMLTemplate,
+		deadline.Format("02 Jan 2006 15:04"),
+		purchase.CreatedAt.Format("02 Jan 2006 15:04"),
+		purchase.Quantity,
+		purchase.PaymentMethod,
+		purchase.TransactionDigits,
+	)
+	payload := EmailPayload{
+		FromEmail: EmailObject{Address: m.from, DisplayName: "Compras"},
+		ToEmail:   []EmailObject{{Address: to}},
+		Subject:   "Tu compra está por vencer",
+		HtmlBody:  htmlBody,
+	}
+
+	return m.send(payload)
+}
+
+func (m *mailerooClient) send(payload EmailPayload) error {
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal email payload: %w", err)
@@ -100,3 +169,16 @@ func (m *mailerooClient) SendPurchaseConfirmation(purchase types.Purchase) error
 
 	return nil
 }
+
+func statusLabel(status types.PurchaseStatus) string {
+	switch status {
+	case types.StatusVerified:
+		return "verificada"
+	case types.StatusCancelled:
+		return "cancelada"
+	case types.StatusRefunded:
+		return "reembolsada"
+	default:
+		return "pendiente"
+	}
+}
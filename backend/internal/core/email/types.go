@@ -6,7 +6,7 @@ type EmailPayload struct {
 	ToEmail     []EmailObject `json:"to"`
 	Subject     string        `json:"subject"`
 	HtmlBody    string        `json:"html"`
-	Attachments []File        `json:"attachments"`
+	Attachments []File        `json:"attachments,omitempty"`
 }
 
 type EmailObject struct {
@@ -89,3 +89,130 @@ const EmailHTMLTemplate = `
   </body>
 </html>
 `
+
+const StatusUpdateHTMLTemplate = `
+<!DOCTYPE html>
+<html lang="es">
+  <head>
+    <meta charset="UTF-8" />
+    <meta name="viewport" content="width=device-width, initial-scale=1.0" />
+    <title>Estado de tu compra</title>
+    <style>
+      body {
+        font-family: Arial, sans-serif;
+        background-color: #f6f6f6;
+        color: #333333;
+        padding: 20px;
+        margin: 0;
+      }
+      .container {
+        background-color: #ffffff;
+        padding: 20px;
+        max-width: 600px;
+        margin: auto;
+        border-radius: 8px;
+        box-shadow: 0 2px 4px rgba(0, 0, 0, 0.1);
+      }
+      h1 {
+        color: #e67e22;
+        font-size: 20px;
+      }
+      .details {
+        margin-top: 20px;
+      }
+      .details p {
+        margin: 8px 0;
+      }
+      .footer {
+        margin-top: 30px;
+        font-size: 12px;
+        color: #999;
+        text-align: center;
+      }
+    </style>
+  </head>
+  <body>
+    <div class="container">
+      <h1>🎟️ Tu compra fue %s</h1>
+
+      <div class="details">
+        <p><strong>📅 Fecha de compra:</strong> %s</p>
+        <p><strong>🎟️ Cantidad de boletos:</strong> %d</p>
+        <p><strong>💳 Método de pago:</strong> %s</p>
+        <p><strong>🔢 Últimos dígitos:</strong> %s</p>
+        <p><strong>📝 Motivo:</strong> %s</p>
+      </div>
+
+      <div class="footer">
+        Este mensaje fue generado automáticamente por el sistema de rifas.
+      </div>
+    </div>
+  </body>
+</html>
+`
+
+const ExpiryWarningHTMLTemplate = `
+<!DOCTYPE html>
+<html lang="es">
+  <head>
+    <meta charset="UTF-8" />
+    <meta name="viewport" content="width=device-width, initial-scale=1.0" />
+    <title>Tu compra está por vencer</title>
+    <style>
+      body {
+        font-family: Arial, sans-serif;
+        background-color: #f6f6f6;
+        color: #333333;
+        padding: 20px;
+        margin: 0;
+      }
+      .container {
+        background-color: #ffffff;
+        padding: 20px;
+        max-width: 600px;
+        margin: auto;
+        border-radius: 8px;
+        box-shadow: 0 2px 4px rgba(0, 0, 0, 0.1);
+      }
+      h1 {
+        color: #e67e22;
+        font-size: 20px;
+      }
+      .details {
+        margin-top: 20px;
+      }
+      .details p {
+        margin: 8px 0;
+      }
+      .footer {
+        margin-top: 30px;
+        font-size: 12px;
+        color: #999;
+        text-align: center;
+      }
+    </style>
+  </head>
+  <body>
+    <div class="container">
+      <h1>⏳ Tu compra aún no ha sido verificada</h1>
+
+      <p>
+        Si no podemos verificar tu pago antes del <strong>%s</strong>, la
+        compra se cancelará y tus números quedarán disponibles de nuevo.
+        Si ya pagaste, contáctanos para revisar tu comprobante.
+      </p>
+
+      <div class="details">
+        <p><strong>📅 Fecha de compra:</strong> %s</p>
+        <p><strong>🎟️ Cantidad de boletos:</strong> %d</p>
+        <p><strong>💳 Método de pago:</strong> %s</p>
+        <p><strong>🔢 Últimos dígitos:</strong> %s</p>
+      </div>
+
+      <div class="footer">
+        Este mensaje fue generado automáticamente por el sistema de rifas.
+      </div>
+    </div>
+  </body>
+</html>
+`
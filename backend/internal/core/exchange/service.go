@@ -0,0 +1,135 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"rifa/backend/internal/core/audit"
+	"rifa/backend/internal/core/price"
+	"rifa/backend/internal/events"
+	"rifa/backend/internal/repository"
+	"rifa/backend/internal/types"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+	"rifa/backend/pkg/rates"
+)
+
+var ErrNoProvider = errors.New("no exchange rate provider configured")
+
+type Service interface {
+	// Record stores a rate typed in by an admin
+	Record(
+		ctx context.Context,
+		actor types.Actor,
+		rate float64,
+	) (types.ExchangeRate, error)
+	// Refresh fetches the rate from the configured provider and stores it
+	Refresh(ctx context.Context, actor types.Actor) (types.ExchangeRate, error)
+	List(
+		ctx context.Context,
+		page,
+		perPage int,
+	) ([]types.ExchangeRate, int, error)
+}
+
+type service struct {
+	repo     repository.ExchangeRateRepository
+	audit    audit.Service
+	prices   price.Service
+	provider rates.RateProvider
+}
+
+func NewService(
+	db database.DB,
+	recorder events.Recorder,
+	opts config.ServiceOpts,
+) Service {
+	provider, err := rates.NewProvider(opts.Price.Rates)
+	if err != nil {
+		log.Printf("exchange rate provider disabled: %v", err)
+	}
+
+	return &service{
+		repo:     repository.NewExchangeRateRepository(db),
+		audit:    audit.NewService(db),
+		prices:   price.NewService(db, recorder, opts),
+		provider: provider,
+	}
+}
+
+func (s *service) Record(
+	ctx context.Context,
+	actor types.Actor,
+	rate float64,
+) (types.ExchangeRate, error) {
+	return s.save(ctx, actor, rate, "manual")
+}
+
+func (s *service) Refresh(
+	ctx context.Context,
+	actor types.Actor,
+) (types.ExchangeRate, error) {
+	if s.provider == nil {
+		return types.ExchangeRate{}, ErrNoProvider
+	}
+
+	rate, err := s.provider.Rate(ctx)
+	if err != nil {
+		return types.ExchangeRate{}, err
+	}
+
+	return s.save(ctx, actor, rate, s.provider.Name())
+}
+
+func (s *service) List(
+	ctx context.Context,
+	page,
+	perPage int,
+) ([]types.ExchangeRate, int, error) {
+	return s.repo.List(ctx, page, perPage)
+}
+
+func (s *service) save(
+	ctx context.Context,
+	actor types.Actor,
+	rate float64,
+	source string,
+) (types.ExchangeRate, error) {
+	saved := types.ExchangeRate{
+		Rate:      rate,
+		Source:    source,
+		CreatedBy: nullableID(actor.ID),
+	}
+	hooks := []repository.TxHook{
+		s.audit.Hook(func() types.AuditEntry {
+			return types.AuditEntry{
+				Actor:      actor,
+				Action:     types.AuditExchangeRateRecorded,
+				TargetType: types.AuditTargetExchangeRate,
+				TargetID:   saved.ID,
+				After:      saved,
+			}
+		}),
+	}
+	priceHook, err := s.prices.RateHook(ctx, actor, &saved)
+	if err != nil {
+		return types.ExchangeRate{}, err
+	}
+	if priceHook != nil {
+		hooks = append(hooks, priceHook)
+	}
+
+	err = s.repo.Save(ctx, &saved, hooks...)
+	if err != nil {
+		return types.ExchangeRate{}, err
+	}
+	return saved, nil
+}
+
+func nullableID(id string) *string {
+	if id == "" {
+		return nil
+	}
+	return &id
+}
@@ -1,16 +1,24 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"io/fs"
+	"log"
 	"log/slog"
 	"net/http"
 	"time"
 
 	"rifa/backend/api"
+	"rifa/backend/internal/core/email"
+	"rifa/backend/internal/core/price"
+	"rifa/backend/internal/core/purchase"
 	"rifa/backend/internal/core/spa"
+	ticket "rifa/backend/internal/core/tickets"
+	"rifa/backend/internal/core/webhook"
+	"rifa/backend/internal/events"
 	"rifa/backend/pkg/config"
-	"rifa/backend/pkg/db"
+	database "rifa/backend/pkg/db"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humachi"
@@ -18,6 +26,7 @@ import (
 	chimdw "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/httprate"
 	"github.com/riandyrn/otelchi"
+	"go.opentelemetry.io/otel"
 )
 
 // FS is an interface that abstracts filesystem operations.
@@ -61,7 +70,37 @@ type HttpServerOptions struct {
 
 	// ServiceOpts have the environment variables to initialize services
 	ServiceOpts config.ServiceOpts
+
+	// Context stops the background jobs of the server once it's done
+	Context context.Context
+}
+
+// streamingPaths answer with long lived responses, so they're exempt from
+// the request timeout
+var streamingPaths = map[string]bool{
+	"/api/tickets/stream":      true,
+	"/api/purchases/export":    true,
+	"/api/admin/events/stream": true,
 }
+
+// timeoutExcept applies chi's Timeout middleware to every path but the given
+// ones
+func timeoutExcept(
+	timeout time.Duration,
+	paths map[string]bool,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withTimeout := chimdw.Timeout(timeout)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if paths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			withTimeout.ServeHTTP(w, r)
+		})
+	}
+}
+
 type HttpServer struct {
 	*chi.Mux
 	*http.Server
@@ -70,7 +109,7 @@ type HttpServer struct {
 }
 
 func NewHttpServer(
-	db db.DB,
+	db database.DB,
 	front http.FileSystem,
 	opts HttpServerOptions,
 ) (*HttpServer, error) {
@@ -78,12 +117,41 @@ func NewHttpServer(
 		return nil, fmt.Errorf("logger is required")
 	}
 
+	emailer := email.NewMailerooClient(
+		opts.ServiceOpts.Email.MailerooApiKey,
+		opts.ServiceOpts.Email.EmailSender,
+		opts.ServiceOpts.Email.EmailReciever,
+		opts.ServiceOpts.Email.EmailURL,
+	)
+	webhooks := webhook.NewService(db, opts.ServiceOpts)
+	bus, err := subscribeEvents(emailer, webhooks)
+	if err != nil {
+		return nil, err
+	}
+	outbox := events.NewOutbox(db)
+	broadcaster := ticket.NewBroadcaster(ticket.NewService(db))
+
+	jobCtx := opts.Context
+	if jobCtx == nil {
+		jobCtx = context.Background()
+	}
+	startJobs(
+		jobCtx,
+		db,
+		bus,
+		outbox,
+		broadcaster,
+		emailer,
+		webhooks,
+		opts.ServiceOpts,
+	)
+
 	router := chi.NewRouter()
 	router.Use(chimdw.Logger)
 	router.Use(chimdw.RequestID)
 	router.Use(chimdw.RealIP)
 	router.Use(chimdw.Recoverer)
-	router.Use(chimdw.Timeout(15 * time.Second))
+	router.Use(timeoutExcept(15*time.Second, streamingPaths))
 	router.Use(httprate.LimitAll(50, 1*time.Second))
 	router.Use(chimdw.Compress(4))
 	router.Use(otelchi.Middleware("rifa", otelchi.WithChiRoutes(router)))
@@ -91,7 +159,14 @@ func NewHttpServer(
 	apiConfig := huma.DefaultConfig("rifa", "1.0.0")
 	apiConfig.CreateHooks = nil
 	humaApi := humachi.New(router, apiConfig)
-	api.RegisterHttpRoutes(humaApi, db, opts.ServiceOpts)
+	api.RegisterHttpRoutes(
+		humaApi,
+		db,
+		bus,
+		outbox,
+		broadcaster,
+		opts.ServiceOpts,
+	)
 
 	router.Get("/", spa.SpaHandler(front))
 	router.NotFound(spa.SpaHandler(front))
@@ -112,3 +187,60 @@ func NewHttpServer(
 
 	return server, nil
 }
+
+// startJobs runs the background jobs of the server until ctx is done: the
+// outbox relay, the webhook deliveries, the announcement of scheduled prices,
+// the expiry of pending purchases and the ticket availability stream
+func startJobs(
+	ctx context.Context,
+	db database.DB,
+	bus *events.Bus,
+	outbox *events.Outbox,
+	broadcaster *ticket.Broadcaster,
+	emailer email.Mailer,
+	webhooks webhook.Service,
+	opts config.ServiceOpts,
+) {
+	listener, _ := db.(database.Listener)
+	go outbox.Run(ctx, bus, opts.Events.OutboxInterval, listener)
+	go webhook.RunRetries(ctx, webhooks, opts.Webhook.RetryInterval)
+	go price.RunSchedule(
+		ctx,
+		price.NewService(db, outbox, opts),
+		opts.Price.ScheduleInterval,
+	)
+	go purchase.RunExpiry(
+		ctx,
+		purchase.NewService(db, emailer, outbox, opts),
+		opts.Purchase.ExpiryInterval,
+	)
+	if listener != nil {
+		go broadcaster.Run(ctx, listener)
+	} else {
+		log.Println("tickets stream disabled: database can't listen")
+	}
+}
+
+// subscribeEvents returns the bus the outbox relays the domain events to,
+// with the subscribers that react to them registered. Audit entries aren't
+// a subscriber: they're written by audit.Hook in the transaction of each
+// mutation, so they can't be lost once it commits.
+func subscribeEvents(
+	emailer email.Mailer,
+	webhooks webhook.Service,
+) (*events.Bus, error) {
+	bus := events.NewBus()
+
+	metrics, err := events.MetricsSubscriber(otel.Meter("rifa/events"))
+	if err != nil {
+		return nil, err
+	}
+	bus.Subscribe(metrics)
+	// Webhook deliveries are stored before an event is marked dispatched,
+	// emails are best effort and may be lost on restart rather than sent
+	// twice
+	bus.SubscribeDurable(webhooks.Handle)
+	bus.SubscribeAsync(events.EmailSubscriber(emailer))
+
+	return bus, nil
+}
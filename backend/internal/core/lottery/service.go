@@ -0,0 +1,88 @@
+package lottery
+
+import (
+	"context"
+	"errors"
+
+	"rifa/backend/internal/core/audit"
+	"rifa/backend/internal/repository"
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+var ErrInvalidLimits = errors.New("invalid lottery limits")
+
+type Service interface {
+	GetActive(ctx context.Context) (types.Lottery, error)
+	// UpdateLimits sets the quantity limits and the pending purchases expiry
+	// of the active lottery
+	UpdateLimits(
+		ctx context.Context,
+		actor types.Actor,
+		limits types.Lottery,
+	) (types.Lottery, error)
+}
+
+type service struct {
+	repo  repository.LotteryRepository
+	audit audit.Service
+}
+
+func NewService(db database.DB) Service {
+	return &service{
+		repo:  repository.NewLotteryRepository(db),
+		audit: audit.NewService(db),
+	}
+}
+
+func (s *service) GetActive(ctx context.Context) (types.Lottery, error) {
+	return s.repo.GetActive(ctx)
+}
+
+func (s *service) UpdateLimits(
+	ctx context.Context,
+	actor types.Actor,
+	limits types.Lottery,
+) (types.Lottery, error) {
+	switch {
+	case limits.MinPerPurchase < 1:
+		return types.Lottery{}, ErrInvalidLimits
+	case limits.MaxPerPurchase != nil &&
+		*limits.MaxPerPurchase < limits.MinPerPurchase:
+		return types.Lottery{}, ErrInvalidLimits
+	case limits.MaxPerUser != nil &&
+		*limits.MaxPerUser < limits.MinPerPurchase:
+		return types.Lottery{}, ErrInvalidLimits
+	case limits.PendingExpiryHours != nil && *limits.PendingExpiryHours < 1:
+		return types.Lottery{}, ErrInvalidLimits
+	}
+
+	before, err := s.repo.GetActive(ctx)
+	if err != nil {
+		return types.Lottery{}, err
+	}
+
+	after := before
+	after.MinPerPurchase = limits.MinPerPurchase
+	after.MaxPerPurchase = limits.MaxPerPurchase
+	after.MaxPerUser = limits.MaxPerUser
+	after.PendingExpiryHours = limits.PendingExpiryHours
+	err = s.repo.UpdateLimits(
+		ctx,
+		after,
+		s.audit.Hook(func() types.AuditEntry {
+			return types.AuditEntry{
+				Actor:      actor,
+				Action:     types.AuditLotteryLimitsUpdated,
+				TargetType: types.AuditTargetLottery,
+				TargetID:   after.ID,
+				Before:     before,
+				After:      after,
+			}
+		}),
+	)
+	if err != nil {
+		return types.Lottery{}, err
+	}
+	return after, nil
+}
@@ -0,0 +1,156 @@
+package paymentmethod
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"rifa/backend/internal/core/audit"
+	"rifa/backend/internal/repository"
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+var (
+	ErrNotFound       = errors.New("payment method not found")
+	ErrAlreadyExists  = errors.New("payment method already exists")
+	ErrInvalidPattern = errors.New("invalid reference pattern")
+)
+
+type Service interface {
+	List(ctx context.Context, onlyEnabled bool) ([]types.PaymentMethod, error)
+	Create(
+		ctx context.Context,
+		actor types.Actor,
+		method types.PaymentMethod,
+	) error
+	Update(
+		ctx context.Context,
+		actor types.Actor,
+		method types.PaymentMethod,
+	) error
+	Delete(ctx context.Context, actor types.Actor, code string) error
+}
+
+type service struct {
+	repo  repository.PaymentMethodRepository
+	audit audit.Service
+}
+
+func NewService(db database.DB) Service {
+	return &service{
+		repo:  repository.NewPaymentMethodRepository(db),
+		audit: audit.NewService(db),
+	}
+}
+
+func (s *service) List(
+	ctx context.Context,
+	onlyEnabled bool,
+) ([]types.PaymentMethod, error) {
+	return s.repo.List(ctx, onlyEnabled)
+}
+
+func (s *service) Create(
+	ctx context.Context,
+	actor types.Actor,
+	method types.PaymentMethod,
+) error {
+	method.Code = strings.ToLower(strings.TrimSpace(method.Code))
+	if !method.ValidPattern() {
+		return ErrInvalidPattern
+	}
+
+	_, err := s.get(ctx, method.Code)
+	if err == nil {
+		return ErrAlreadyExists
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	err = s.repo.Create(
+		ctx,
+		&method,
+		s.audited(actor, types.AuditPaymentMethodCreated, method.Code, nil, &method),
+	)
+	// A concurrent create of the same code gets past the check above and is
+	// refused by the unique index
+	if database.IsUniqueViolation(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+func (s *service) Update(
+	ctx context.Context,
+	actor types.Actor,
+	method types.PaymentMethod,
+) error {
+	if !method.ValidPattern() {
+		return ErrInvalidPattern
+	}
+
+	before, err := s.get(ctx, method.Code)
+	if err != nil {
+		return err
+	}
+	method.ID = before.ID
+	method.Code = before.Code
+
+	return s.repo.Update(
+		ctx,
+		method,
+		s.audited(actor, types.AuditPaymentMethodUpdated, method.Code, before, method),
+	)
+}
+
+func (s *service) Delete(
+	ctx context.Context,
+	actor types.Actor,
+	code string,
+) error {
+	before, err := s.get(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Delete(
+		ctx,
+		before.Code,
+		s.audited(actor, types.AuditPaymentMethodDeleted, before.Code, before, nil),
+	)
+}
+
+func (s *service) get(
+	ctx context.Context,
+	code string,
+) (types.PaymentMethod, error) {
+	method, err := s.repo.GetByCode(ctx, code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.PaymentMethod{}, ErrNotFound
+	}
+	return method, err
+}
+
+// audited returns the hook recording a payment method change in its
+// transaction
+func (s *service) audited(
+	actor types.Actor,
+	action types.AuditAction,
+	code string,
+	before,
+	after any,
+) repository.TxHook {
+	return s.audit.Hook(func() types.AuditEntry {
+		return types.AuditEntry{
+			Actor:      actor,
+			Action:     action,
+			TargetType: types.AuditTargetPaymentMethod,
+			TargetID:   code,
+			Before:     before,
+			After:      after,
+		}
+	})
+}
@@ -0,0 +1,29 @@
+package price
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunSchedule calls AnnounceScheduled every interval until ctx is done. A
+// zero or negative interval disables the announcements.
+func RunSchedule(ctx context.Context, srv Service, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := srv.AnnounceScheduled(ctx); err != nil {
+			log.Printf("failed to announce scheduled prices: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
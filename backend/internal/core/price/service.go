@@ -2,31 +2,366 @@ package price
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"time"
 
+	"rifa/backend/internal/core/audit"
+	"rifa/backend/internal/events"
 	"rifa/backend/internal/repository"
 	"rifa/backend/internal/types"
+	"rifa/backend/pkg/config"
 	database "rifa/backend/pkg/db"
+	"rifa/backend/pkg/utils"
+)
+
+var (
+	ErrInvalidTiers = errors.New("invalid price tiers")
+	// ErrNoPrices is returned when the active lottery has no prices in effect
+	ErrNoPrices = errors.New("no prices in effect")
 )
 
 type Service interface {
+	// GetPrices returns the prices in effect for the active lottery. When Bs
+	// prices are derived, the Bs amount is the USD amount converted with the
+	// latest exchange rate.
 	GetPrices(ctx context.Context) (types.Prices, error)
-	Update(ctx context.Context, bs, usd float64) error
+	// GetPriceTable returns the current unit prices along with the bundles of
+	// the active lottery, converted the same way as GetPrices
+	GetPriceTable(ctx context.Context) (types.PriceTable, error)
+	UpdateTiers(
+		ctx context.Context,
+		actor types.Actor,
+		tiers []types.PriceTier,
+	) error
+	// Update stores new prices for the active lottery, effective right away
+	// or from prices.EffectiveFrom when it is set. Prices in effect right
+	// away are announced with a PriceChanged event, scheduled ones by
+	// AnnounceScheduled once they come into effect.
+	Update(ctx context.Context, actor types.Actor, prices types.Prices) error
+	// AnnounceScheduled records a PriceChanged event for every scheduled
+	// price that came into effect since it last ran
+	AnnounceScheduled(ctx context.Context) error
+	// RateHook returns the hook recording, in the transaction storing rate,
+	// how the new rate moves the Bs prices of the active lottery. It's nil
+	// when Bs prices aren't derived or there are no prices yet.
+	RateHook(
+		ctx context.Context,
+		actor types.Actor,
+		rate *types.ExchangeRate,
+	) (repository.TxHook, error)
+	GetHistory(
+		ctx context.Context,
+		page,
+		perPage int,
+	) ([]types.PriceChange, int, error)
 }
 
 type service struct {
-	repo repository.PriceRepository
+	repo       repository.PriceRepository
+	rateRepo   repository.ExchangeRateRepository
+	ticketRepo repository.TicketRepository
+	audit      audit.Service
+	events     events.Recorder
+	priceOpts  config.PriceOpts
 }
 
-func NewService(db database.DB) Service {
+func NewService(
+	db database.DB,
+	recorder events.Recorder,
+	opts config.ServiceOpts,
+) Service {
 	return &service{
-		repo: repository.NewPriceRepository(db),
+		repo:       repository.NewPriceRepository(db),
+		rateRepo:   repository.NewExchangeRateRepository(db),
+		ticketRepo: repository.NewTicketRepository(db),
+		audit:      audit.NewService(db),
+		events:     recorder,
+		priceOpts:  opts.Price,
 	}
 }
 
 func (s *service) GetPrices(ctx context.Context) (types.Prices, error) {
-	return s.repo.GetLatestPrices(ctx)
+	lotteryID, err := s.ticketRepo.GetActiveLotteryID(ctx)
+	if err != nil {
+		return types.Prices{}, err
+	}
+
+	prices, err := s.repo.GetLatestPrices(ctx, lotteryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.Prices{}, ErrNoPrices
+	}
+	if err != nil {
+		return types.Prices{}, err
+	}
+
+	toBs, err := s.bsConverter(ctx)
+	if err != nil {
+		return types.Prices{}, err
+	}
+	if toBs != nil {
+		prices.BsAmount = toBs(prices.UsdAmount)
+	}
+	return prices, nil
 }
 
-func (s *service) Update(ctx context.Context, bs, usd float64) error {
-	return s.repo.Save(ctx, types.Prices{BsAmount: bs, UsdAmount: usd})
+func (s *service) GetPriceTable(ctx context.Context) (types.PriceTable, error) {
+	prices, err := s.GetPrices(ctx)
+	if err != nil {
+		return types.PriceTable{}, err
+	}
+
+	tiers, err := s.repo.GetTiers(ctx, prices.LotteryID)
+	if err != nil {
+		return types.PriceTable{}, err
+	}
+
+	toBs, err := s.bsConverter(ctx)
+	if err != nil {
+		return types.PriceTable{}, err
+	}
+	for i, tier := range tiers {
+		if toBs != nil && tier.UsdAmount != nil {
+			bs := toBs(*tier.UsdAmount)
+			tiers[i].BsAmount = &bs
+		}
+	}
+
+	return types.PriceTable{Prices: prices, Tiers: tiers}, nil
+}
+
+func (s *service) UpdateTiers(
+	ctx context.Context,
+	actor types.Actor,
+	tiers []types.PriceTier,
+) error {
+	seen := map[int]bool{}
+	for _, tier := range tiers {
+		fixed := tier.BsAmount != nil && tier.UsdAmount != nil
+		partial := (tier.BsAmount == nil) != (tier.UsdAmount == nil)
+		discounted := tier.PaidQuantity != nil
+		switch {
+		case tier.Quantity < 2, seen[tier.Quantity], partial:
+			return ErrInvalidTiers
+		case discounted == fixed:
+			// A bundle is either "N for the price of M" or a fixed amount
+			return ErrInvalidTiers
+		case discounted &&
+			(*tier.PaidQuantity < 1 || *tier.PaidQuantity >= tier.Quantity):
+			return ErrInvalidTiers
+		case fixed && (*tier.BsAmount <= 0 || *tier.UsdAmount <= 0):
+			return ErrInvalidTiers
+		}
+		seen[tier.Quantity] = true
+	}
+
+	lotteryID, err := s.ticketRepo.GetActiveLotteryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	before, err := s.repo.GetTiers(ctx, lotteryID)
+	if err != nil {
+		return err
+	}
+
+	// Bundles leave the unit prices as they are
+	var previous *types.Prices
+	prices, err := s.GetPrices(ctx)
+	switch {
+	case err == nil:
+		previous = &prices
+	case errors.Is(err, ErrNoPrices):
+		prices = types.Prices{LotteryID: lotteryID}
+	default:
+		return err
+	}
+
+	return s.repo.ReplaceTiers(
+		ctx,
+		lotteryID,
+		tiers,
+		s.audit.Hook(func() types.AuditEntry {
+			return types.AuditEntry{
+				Actor:      actor,
+				Action:     types.AuditPriceTiersUpdated,
+				TargetType: types.AuditTargetPrice,
+				TargetID:   lotteryID,
+				Before:     before,
+				After:      tiers,
+			}
+		}),
+		func(ctx context.Context, tx database.Tx) error {
+			return s.events.Record(ctx, tx, events.PriceChanged{
+				Cause:    events.PriceCauseTiers,
+				Prices:   prices,
+				Previous: previous,
+				Tiers:    tiers,
+				Actor:    actor,
+			})
+		},
+	)
+}
+
+func (s *service) RateHook(
+	ctx context.Context,
+	actor types.Actor,
+	rate *types.ExchangeRate,
+) (repository.TxHook, error) {
+	if !s.priceOpts.DeriveBs {
+		return nil, nil
+	}
+
+	// Until the rate is stored, the prices are the ones derived from the
+	// previous rate
+	previous, err := s.GetPrices(ctx)
+	if errors.Is(err, ErrNoPrices) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, tx database.Tx) error {
+		prices := previous
+		prices.BsAmount = s.toBs(rate.Rate)(prices.UsdAmount)
+		if prices.BsAmount == previous.BsAmount {
+			return nil
+		}
+		return s.events.Record(ctx, tx, events.PriceChanged{
+			Cause:    events.PriceCauseRate,
+			Prices:   prices,
+			Previous: &previous,
+			Actor:    actor,
+		})
+	}, nil
+}
+
+// bsConverter returns the function turning USD amounts into rounded Bs
+// amounts with the latest exchange rate, or nil when Bs prices aren't derived
+// or no rate was recorded yet, in which case stored Bs amounts apply
+func (s *service) bsConverter(ctx context.Context) (func(float64) float64, error) {
+	if !s.priceOpts.DeriveBs {
+		return nil, nil
+	}
+
+	rate, err := s.rateRepo.GetLatest(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toBs(rate.Rate), nil
+}
+
+// toBs returns the function turning USD amounts into Bs amounts with rate,
+// rounded as configured
+func (s *service) toBs(rate float64) func(float64) float64 {
+	return func(usd float64) float64 {
+		return utils.RoundToStep(
+			usd*rate,
+			s.priceOpts.RoundingStep,
+			s.priceOpts.RoundingMode,
+		)
+	}
+}
+
+func (s *service) Update(
+	ctx context.Context,
+	actor types.Actor,
+	prices types.Prices,
+) error {
+	lotteryID, err := s.ticketRepo.GetActiveLotteryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var previous *types.Prices
+	current, err := s.repo.GetLatestPrices(ctx, lotteryID)
+	switch {
+	case err == nil:
+		previous = &current
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	prices.LotteryID = lotteryID
+	if actor.ID != "" {
+		prices.CreatedBy = &actor.ID
+	}
+	now := time.Now()
+	if prices.EffectiveFrom.IsZero() {
+		prices.EffectiveFrom = now
+	}
+	// Scheduled prices are announced by AnnounceScheduled once in effect
+	hooks := []repository.TxHook{}
+	if !prices.EffectiveFrom.After(now) {
+		hooks = append(hooks, func(ctx context.Context, tx database.Tx) error {
+			err := s.repo.MarkAnnounced(ctx, tx, prices.ID)
+			if err != nil {
+				return err
+			}
+			return s.events.Record(ctx, tx, events.PriceChanged{
+				Cause:    events.PriceCauseUpdate,
+				Prices:   prices,
+				Previous: previous,
+				Actor:    actor,
+			})
+		})
+	}
+	hooks = append(
+		hooks,
+		s.audit.Hook(func() types.AuditEntry {
+			var before any
+			if previous != nil {
+				before = *previous
+			}
+			return types.AuditEntry{
+				Actor:      actor,
+				Action:     types.AuditPriceUpdated,
+				TargetType: types.AuditTargetPrice,
+				TargetID:   prices.ID,
+				Before:     before,
+				After:      prices,
+			}
+		}),
+	)
+	return s.repo.Save(ctx, &prices, hooks...)
+}
+
+func (s *service) AnnounceScheduled(ctx context.Context) error {
+	return s.repo.AnnounceDue(
+		ctx,
+		func(
+			ctx context.Context,
+			tx database.Tx,
+			due []types.ScheduledPrice,
+		) error {
+			for _, scheduled := range due {
+				err := s.events.Record(ctx, tx, events.PriceChanged{
+					Cause:    events.PriceCauseScheduled,
+					Prices:   scheduled.Prices,
+					Previous: scheduled.Previous,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
+
+func (s *service) GetHistory(
+	ctx context.Context,
+	page,
+	perPage int,
+) ([]types.PriceChange, int, error) {
+	lotteryID, err := s.ticketRepo.GetActiveLotteryID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.repo.GetHistory(ctx, lotteryID, page, perPage)
 }
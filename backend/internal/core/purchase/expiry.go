@@ -0,0 +1,104 @@
+package purchase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"rifa/backend/internal/events"
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+// expiryReason is sent to buyers whose purchase was cancelled automatically
+const expiryReason = "El pago no fue verificado antes del plazo de la rifa"
+
+// RunExpiry calls ExpireStale every interval until ctx is done. A zero or
+// negative interval disables the expiry.
+func RunExpiry(ctx context.Context, srv Service, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := srv.ExpireStale(ctx); err != nil {
+			log.Printf("failed to expire pending purchases: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *service) ExpireStale(ctx context.Context) error {
+	expiring, err := s.repo.ListExpiring(ctx, s.opts.ExpiryWarning)
+	if err != nil {
+		return err
+	}
+	if len(expiring) > 0 {
+		ids := make([]string, 0, len(expiring))
+		for _, e := range expiring {
+			ids = append(ids, e.ID)
+		}
+		// Marked before sending so a failing mailer can't spam buyers
+		err = s.repo.MarkExpiryWarned(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range expiring {
+			go func(e types.ExpiringPurchase) {
+				err := s.emailer.SendPurchaseExpiryWarning(
+					e.BuyerEmail,
+					e.Purchase,
+					e.Deadline,
+				)
+				if err != nil {
+					log.Println(err)
+				}
+			}(e)
+		}
+	}
+
+	expired, err := s.repo.ListExpired(ctx)
+	if err != nil || len(expired) == 0 {
+		return err
+	}
+
+	_, err = s.repo.CancelPending(
+		ctx,
+		expired,
+		func(
+			ctx context.Context,
+			tx database.Tx,
+			applied []types.StatusChange,
+		) error {
+			recorded := make([]events.Event, 0, len(applied))
+			for _, change := range applied {
+				recorded = append(
+					recorded,
+					events.NewExpiredEvent(change, expiryReason),
+				)
+			}
+			err := s.events.Record(ctx, tx, recorded...)
+			if err != nil {
+				return err
+			}
+			return s.auditStatusChanges(
+				ctx,
+				tx,
+				applied,
+				types.AuditPurchaseExpired,
+				types.StatusCancelled,
+				expiryReason,
+				types.Actor{},
+			)
+		},
+	)
+	return err
+}
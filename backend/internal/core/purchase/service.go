@@ -2,47 +2,146 @@ package purchase
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"strings"
 	"time"
 
 	"rifa/backend/api/httpx/dto"
 	"rifa/backend/api/httpx/form"
+	"rifa/backend/internal/core/audit"
+	"rifa/backend/internal/core/coupon"
 	"rifa/backend/internal/core/email"
+	"rifa/backend/internal/core/price"
+	"rifa/backend/internal/core/referral"
+	"rifa/backend/internal/events"
 	"rifa/backend/internal/repository"
 	"rifa/backend/internal/types"
+	"rifa/backend/pkg/config"
 	database "rifa/backend/pkg/db"
+	"rifa/backend/pkg/spreadsheet"
 	"rifa/backend/pkg/utils"
+
+	"github.com/google/uuid"
 )
 
 type Service interface {
 	Create(ctx context.Context, req *form.CreatePurchaseRequest) error
+	// GetAll lists a page of purchases, their count and the cursor of the
+	// next page, empty on the last one
 	GetAll(
 		ctx context.Context,
 		filters dto.GetAllPurchases,
-	) ([]form.Purchases, int, error)
-	UpdateStatus(ctx context.Context, purchaseID string, status string) error
+	) ([]form.Purchases, int, string, error)
+	// Export writes the purchases matching filters to w, one row each after
+	// a header row
+	Export(
+		ctx context.Context,
+		filters dto.PurchaseFilters,
+		w spreadsheet.Writer,
+	) error
+	UpdateStatus(
+		ctx context.Context,
+		actor types.Actor,
+		purchaseID string,
+		status string,
+	) error
+	BulkUpdateStatus(
+		ctx context.Context,
+		actor types.Actor,
+		purchaseIDs []string,
+		status types.PurchaseStatus,
+		reason string,
+	) ([]types.StatusChange, error)
+	// GetLeaderboard ranks the buyers of a lottery, the active one when
+	// filters doesn't name any
 	GetLeaderboard(
 		ctx context.Context,
 		filters dto.GetMostPurchases,
-	) ([]form.MostPurchases, error)
+	) ([]form.MostPurchases, int, error)
+	// GetPublicLeaderboard lists the top limit buyers of the active lottery
+	// by verified tickets, with their names masked
+	GetPublicLeaderboard(
+		ctx context.Context,
+		limit int,
+	) ([]form.PublicLeader, error)
 	FindUserPurchasesByTicket(
 		ctx context.Context,
 		ticketNumber string,
 	) (form.SearchResult, error)
+	// ExpireStale warns the buyers of pending purchases close to the deadline
+	// of their lottery and cancels the ones past it
+	ExpireStale(ctx context.Context) error
+}
+
+// Policies for suspicious purchases, validated when the config is loaded
+const (
+	PolicyFlag   = config.PolicyFlag
+	PolicyReject = config.PolicyReject
+)
+
+var (
+	ErrPurchaseNotFound  = errors.New("purchase not found")
+	ErrIllegalTransition = errors.New("illegal purchase status transition")
+	ErrDuplicatePayment  = errors.New("payment reference already used")
+	ErrAmountMismatch    = errors.New("purchase amount doesn't match the price")
+	ErrPaymentMethod     = errors.New("unknown or disabled payment method")
+	ErrInvalidReference  = errors.New("invalid transaction reference")
+	ErrBelowMinimum      = errors.New("quantity below the purchase minimum")
+	ErrAboveMaximum      = errors.New("quantity above the purchase maximum")
+	ErrUserLimit         = errors.New("quantity above the user limit")
+)
+
+// LimitError reports a quantity outside the lottery limits along with the
+// quantity allowed: the minimum, the maximum or what the user has left
+type LimitError struct {
+	Err   error
+	Limit int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v (%d)", e.Err, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
 }
 
 type service struct {
 	repo       repository.PurchaseRepository
 	ticketRepo repository.TicketRepository
+	lotteries  repository.LotteryRepository
+	prices     price.Service
+	methodRepo repository.PaymentMethodRepository
+	coupons    coupon.Service
+	referrals  referral.Service
 	emailer    email.Mailer
+	events     events.Recorder
+	audit      audit.Service
+	opts       config.PurchaseOpts
 }
 
-func NewService(db database.DB, emailClient email.Mailer) Service {
+func NewService(
+	db database.DB,
+	emailClient email.Mailer,
+	recorder events.Recorder,
+	opts config.ServiceOpts,
+) Service {
 	return &service{
 		repo:       repository.NewPurchaseRepository(db),
 		ticketRepo: repository.NewTicketRepository(db),
+		lotteries:  repository.NewLotteryRepository(db),
+		prices:     price.NewService(db, events.Discard, opts),
+		methodRepo: repository.NewPaymentMethodRepository(db),
+		coupons:    coupon.NewService(db, opts),
+		referrals:  referral.NewService(db, opts),
 		emailer:    emailClient,
+		events:     recorder,
+		audit:      audit.NewService(db),
+		opts:       opts.Purchase,
 	}
 }
 
@@ -50,77 +149,403 @@ func (s *service) Create(
 	ctx context.Context,
 	req *form.CreatePurchaseRequest,
 ) error {
-	compressedScreenshot, err := utils.CompressToJPG(req.PaymentScreenshot)
+	method, err := s.methodRepo.GetByCode(ctx, req.PaymentMethod)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !method.Enabled) {
+		return ErrPaymentMethod
+	}
+	if err != nil {
+		return err
+	}
+	if !method.ValidReference(req.TransactionDigits) {
+		return ErrInvalidReference
+	}
+
+	lottery, err := s.lotteries.GetActive(ctx)
+	if err != nil {
+		return err
+	}
+	err = checkLimits(lottery, req.Quantity)
+	if err != nil {
+		return err
+	}
+
+	var promo *types.Coupon
+	if req.CouponCode != "" {
+		c, err := s.coupons.Check(ctx, req.CouponCode, req.UserID)
+		if err != nil {
+			return err
+		}
+		promo = &c
+	}
+
+	compressedScreenshot, screenshotHash, err := utils.CompressAndHashJPG(
+		req.PaymentScreenshot,
+	)
 	if err != nil {
 		return err
 	}
 
 	purchase := &types.Purchase{
 		UserID:            req.UserID,
+		LotteryID:         lottery.ID,
 		Quantity:          req.Quantity,
 		MontoBs:           req.MontoBs,
 		MontoUSD:          req.MontoUSD,
-		PaymentMethod:     req.PaymentMethod,
+		PaymentMethod:     method.Code,
 		TransactionDigits: req.TransactionDigits,
 		PaymentScreenshot: compressedScreenshot,
+		ScreenshotHash:    screenshotHash,
 		Status:            types.StatusPending,
 		CreatedAt:         time.Now(),
 	}
 
-	purchaseID, err := s.repo.Create(ctx, purchase)
+	table, err := s.prices.GetPriceTable(ctx)
 	if err != nil {
 		return err
 	}
+	purchase.PriceID = &table.ID
+	expected := table.Total(purchase.Quantity, method.Currency)
+	if promo != nil {
+		expected -= promo.Discount(expected, method.Currency, table.Prices)
+		purchase.BonusTickets = promo.BonusTickets()
+	}
+	if !amountMatches(purchase, method.Currency, expected) {
+		if s.opts.AmountMismatchPolicy == PolicyReject {
+			return ErrAmountMismatch
+		}
+		purchase.AmountMismatch = true
+	}
 
-	lotteryID, err := s.ticketRepo.GetActiveLotteryID(ctx)
-	if err != nil {
+	// The purchase, its coupon redemption and its tickets are stored
+	// together, so a coupon that ran out or a number taken in the meantime
+	// leaves nothing behind
+	var hooks []repository.TxHook
+	if promo != nil {
+		hooks = append(hooks, func(ctx context.Context, tx database.Tx) error {
+			return s.coupons.Redeem(ctx, tx, *promo, req.UserID, purchase.ID)
+		})
+	}
+	hooks = append(hooks,
+		func(ctx context.Context, tx database.Tx) error {
+			_, err := s.ticketRepo.AssignTicketsTx(
+				ctx,
+				tx,
+				lottery.ID,
+				req.UserID,
+				purchase.ID,
+				req.SelectedNumbers,
+				req.Quantity+purchase.BonusTickets,
+			)
+			return err
+		},
+		func(ctx context.Context, tx database.Tx) error {
+			return s.events.Record(ctx, tx, events.PurchaseCreated{
+				Purchase: events.NewPurchase(*purchase),
+			})
+		},
+	)
+	check := func(ctx context.Context, tx database.Tx) error {
+		err := s.checkUserLimit(ctx, tx, lottery, purchase)
+		if err != nil {
+			return err
+		}
+		return s.checkDuplicate(ctx, tx, purchase)
+	}
+	return s.repo.Create(ctx, purchase, check, hooks...)
+}
+
+// checkDuplicate looks within tx for an earlier purchase paid with the same
+// reference, rejecting the purchase or marking it per the duplicate policy
+func (s *service) checkDuplicate(
+	ctx context.Context,
+	tx database.Tx,
+	purchase *types.Purchase,
+) error {
+	duplicateOf, err := s.repo.FindDuplicate(ctx, tx, purchase)
+	if err != nil || duplicateOf == "" {
 		return err
 	}
+	if s.opts.DuplicatePolicy == PolicyReject {
+		return ErrDuplicatePayment
+	}
+	purchase.DuplicateOf = &duplicateOf
+	return nil
+}
+
+// checkLimits verifies the quantity of a new purchase against the per
+// purchase limits of the lottery
+func checkLimits(lottery types.Lottery, quantity int) error {
+	if quantity < lottery.MinPerPurchase {
+		return &LimitError{Err: ErrBelowMinimum, Limit: lottery.MinPerPurchase}
+	}
+	// Nobody can buy more tickets than the lottery has, which also bounds
+	// the work of pricing the purchase
+	maxQuantity := types.TicketPool
+	if lottery.MaxPerPurchase != nil {
+		maxQuantity = min(maxQuantity, *lottery.MaxPerPurchase)
+	}
+	if quantity > maxQuantity {
+		return &LimitError{Err: ErrAboveMaximum, Limit: maxQuantity}
+	}
+	return nil
+}
+
+// checkUserLimit verifies within tx that the tickets of the purchase, bonus
+// ones included, fit in what's left of the per user limit of the lottery
+func (s *service) checkUserLimit(
+	ctx context.Context,
+	tx database.Tx,
+	lottery types.Lottery,
+	purchase *types.Purchase,
+) error {
+	if lottery.MaxPerUser == nil {
+		return nil
+	}
 
-	_, err = s.ticketRepo.AssignTickets(
+	bought, err := s.repo.CountUserTickets(
 		ctx,
-		lotteryID,
-		req.UserID,
-		purchaseID,
-		req.SelectedNumbers,
-		req.Quantity,
+		tx,
+		purchase.UserID,
+		lottery.ID,
 	)
 	if err != nil {
 		return err
 	}
+	quantity := purchase.Quantity + purchase.BonusTickets
+	if bought+quantity > *lottery.MaxPerUser {
+		left := max(*lottery.MaxPerUser-bought, 0)
+		return &LimitError{Err: ErrUserLimit, Limit: left}
+	}
+	return nil
+}
 
-	go func(p *types.Purchase) {
-		err := s.emailer.SendPurchaseConfirmation(*p)
+func (s *service) GetAll(
+	ctx context.Context,
+	filters dto.GetAllPurchases,
+) ([]form.Purchases, int, string, error) {
+	var after *types.Cursor
+	if filters.Cursor != "" {
+		cursor, err := types.DecodeCursor(filters.Cursor)
 		if err != nil {
-			log.Println(err)
-		} else {
-			fmt.Println("New purchase received and email send! ")
+			return nil, 0, "", err
 		}
-	}(purchase)
+		after = &cursor
+	}
 
-	return nil
+	purchases, total, next, err := s.repo.GetAll(ctx, filters, after)
+	if err != nil || next == nil {
+		return purchases, total, "", err
+	}
+	return purchases, total, next.Encode(), nil
 }
 
-func (s *service) GetAll(
+// exportHeader names the columns written by Export
+var exportHeader = []any{
+	"ID",
+	"Fecha",
+	"Estado",
+	"Rifa",
+	"Nombre",
+	"Correo",
+	"Telefono",
+	"Cantidad",
+	"Boletos extra",
+	"Numeros",
+	"Monto Bs",
+	"Monto USD",
+	"Metodo de pago",
+	"Referencia",
+	"Cupon",
+}
+
+func (s *service) Export(
 	ctx context.Context,
-	filters dto.GetAllPurchases,
-) ([]form.Purchases, int, error) {
-	return s.repo.GetAll(ctx, filters)
+	filters dto.PurchaseFilters,
+	w spreadsheet.Writer,
+) error {
+	if err := w.WriteRow(exportHeader...); err != nil {
+		return err
+	}
+
+	return s.repo.Export(ctx, filters, func(p types.PurchaseExport) error {
+		return w.WriteRow(
+			p.ID,
+			p.CreatedAt,
+			string(p.Status),
+			p.LotteryName,
+			p.BuyerName,
+			p.BuyerEmail,
+			p.BuyerPhone,
+			p.Quantity,
+			p.BonusTickets,
+			strings.Join(utils.ConvertToStrSlice(p.Tickets), " "),
+			p.MontoBs,
+			p.MontoUSD,
+			p.PaymentMethod,
+			p.TransactionDigits,
+			p.Coupon,
+		)
+	})
 }
 
 func (s *service) UpdateStatus(
 	ctx context.Context,
+	actor types.Actor,
 	purchaseID,
 	status string,
 ) error {
-	return s.repo.UpdateStatus(ctx, purchaseID, status)
+	changes, err := s.BulkUpdateStatus(
+		ctx,
+		actor,
+		[]string{purchaseID},
+		types.PurchaseStatus(status),
+		"",
+	)
+	if err != nil {
+		return err
+	}
+
+	switch changes[0].Result {
+	case types.StatusChangeNotFound:
+		return ErrPurchaseNotFound
+	case types.StatusChangeIllegal:
+		return ErrIllegalTransition
+	}
+	return nil
+}
+
+// BulkUpdateStatus applies the same status transition to several purchases in
+// one transaction, then audits and notifies the buyer of every applied change.
+func (s *service) BulkUpdateStatus(
+	ctx context.Context,
+	actor types.Actor,
+	purchaseIDs []string,
+	status types.PurchaseStatus,
+	reason string,
+) ([]types.StatusChange, error) {
+	// Malformed or repeated ids are reported without reaching the database
+	seen := map[string]bool{}
+	valid := []string{}
+	for _, id := range purchaseIDs {
+		if seen[id] || uuid.Validate(id) != nil {
+			continue
+		}
+		seen[id] = true
+		valid = append(valid, id)
+	}
+
+	applied := map[string]types.StatusChange{}
+	if len(valid) > 0 {
+		changes, err := s.repo.UpdateStatuses(
+			ctx,
+			valid,
+			status,
+			func(
+				ctx context.Context,
+				tx database.Tx,
+				applied []types.StatusChange,
+			) error {
+				recorded := make([]events.Event, 0, len(applied))
+				for _, change := range applied {
+					recorded = append(
+						recorded,
+						events.NewStatusEvent(change, status, reason, actor),
+					)
+				}
+				err := s.events.Record(ctx, tx, recorded...)
+				if err != nil {
+					return err
+				}
+				return s.auditStatusChanges(
+					ctx,
+					tx,
+					applied,
+					types.AuditPurchaseStatusUpdated,
+					status,
+					reason,
+					actor,
+				)
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range changes {
+			applied[change.PurchaseID] = change
+		}
+	}
+
+	results := make([]types.StatusChange, 0, len(purchaseIDs))
+	for _, id := range purchaseIDs {
+		change, ok := applied[id]
+		if !ok {
+			change = types.StatusChange{
+				PurchaseID: id,
+				Result:     types.StatusChangeNotFound,
+			}
+		}
+		results = append(results, change)
+	}
+
+	for _, change := range applied {
+		if change.Result != types.StatusChangeApplied {
+			continue
+		}
+		if status == types.StatusVerified {
+			err := s.referrals.RewardReferrer(ctx, *change.Purchase)
+			if err != nil {
+				log.Printf(
+					"failed to reward referrer of purchase %s: %v",
+					change.PurchaseID,
+					err,
+				)
+			}
+		}
+	}
+
+	return results, nil
 }
 
 func (s *service) GetLeaderboard(
 	ctx context.Context,
 	filters dto.GetMostPurchases,
-) ([]form.MostPurchases, error) {
-	return s.repo.GetLeaderboard(ctx, filters)
+) ([]form.MostPurchases, int, error) {
+	lotteryID := filters.LotteryID
+	if lotteryID == "" {
+		var err error
+		lotteryID, err = s.ticketRepo.GetActiveLotteryID(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return s.repo.GetLeaderboard(ctx, lotteryID, filters)
+}
+
+func (s *service) GetPublicLeaderboard(
+	ctx context.Context,
+	limit int,
+) ([]form.PublicLeader, error) {
+	leaderboard, _, err := s.GetLeaderboard(
+		ctx,
+		dto.GetMostPurchases{ItemCount: limit},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	leaders := []form.PublicLeader{}
+	for i, entry := range leaderboard {
+		// Buyers with only pending purchases are ranked last
+		if entry.Quantity == 0 {
+			break
+		}
+		leaders = append(leaders, form.PublicLeader{
+			Position: i + 1,
+			Name:     utils.MaskName(entry.User.Name),
+			Quantity: entry.Quantity,
+		})
+	}
+	return leaders, nil
 }
 
 func (s *service) FindUserPurchasesByTicket(
@@ -139,3 +564,48 @@ func (s *service) FindUserPurchasesByTicket(
 
 	return user, nil
 }
+
+// auditStatusChanges records every applied status change in tx
+func (s *service) auditStatusChanges(
+	ctx context.Context,
+	tx database.Tx,
+	applied []types.StatusChange,
+	action types.AuditAction,
+	status types.PurchaseStatus,
+	reason string,
+	actor types.Actor,
+) error {
+	for _, change := range applied {
+		after := map[string]any{"status": status}
+		if reason != "" {
+			after["reason"] = reason
+		}
+		err := s.audit.Record(ctx, tx, types.AuditEntry{
+			Actor:      actor,
+			Action:     action,
+			TargetType: types.AuditTargetPurchase,
+			TargetID:   change.PurchaseID,
+			Before:     map[string]any{"status": change.Purchase.Status},
+			After:      after,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// amountMatches checks the amount paid in the currency of the purchase payment
+// method against the expected amount, to the cent
+func amountMatches(
+	p *types.Purchase,
+	currency types.Currency,
+	expected float64,
+) bool {
+	paid := p.MontoBs
+	if currency == types.CurrencyUSD {
+		paid = p.MontoUSD
+	}
+
+	return math.Round(paid*100) == math.Round(expected*100)
+}
@@ -0,0 +1,109 @@
+package referral
+
+import (
+	"context"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/api/httpx/form"
+	"rifa/backend/internal/repository"
+	"rifa/backend/internal/types"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+	"rifa/backend/pkg/utils"
+)
+
+type Service interface {
+	// RewardReferrer assigns the configured free tickets to whoever referred
+	// the buyer of a verified purchase. Only the first verified purchase of a
+	// referred user earns a reward, paid in the lottery of that purchase.
+	RewardReferrer(ctx context.Context, purchase types.Purchase) error
+	GetSummary(ctx context.Context, userID string) (form.ReferralSummary, error)
+	GetTopReferrers(
+		ctx context.Context,
+		filters dto.GetTopReferrers,
+	) ([]form.TopReferrer, int, error)
+}
+
+type service struct {
+	repo       repository.ReferralRepository
+	ticketRepo repository.TicketRepository
+	opts       config.ReferralOpts
+}
+
+func NewService(db database.DB, opts config.ServiceOpts) Service {
+	return &service{
+		repo:       repository.NewReferralRepository(db),
+		ticketRepo: repository.NewTicketRepository(db),
+		opts:       opts.Referral,
+	}
+}
+
+func (s *service) RewardReferrer(
+	ctx context.Context,
+	purchase types.Purchase,
+) error {
+	if s.opts.RewardTickets <= 0 {
+		return nil
+	}
+
+	referrerID, err := s.repo.GetReferrerID(ctx, purchase.UserID)
+	if err != nil || referrerID == "" {
+		return err
+	}
+
+	// The reward belongs to the lottery the purchase was made in, which may
+	// no longer be the active one by the time it's verified
+	lotteryID := purchase.LotteryID
+
+	reward := &types.ReferralReward{
+		ReferrerID: referrerID,
+		ReferredID: purchase.UserID,
+		PurchaseID: purchase.ID,
+		LotteryID:  lotteryID,
+	}
+	// The reward and its tickets are stored together, so a failure leaves
+	// nothing behind and the next verified purchase can try again
+	_, err = s.repo.CreateReward(
+		ctx,
+		reward,
+		func(ctx context.Context, tx database.Tx) error {
+			tickets, err := s.ticketRepo.AssignTicketsTx(
+				ctx,
+				tx,
+				lotteryID,
+				referrerID,
+				"",
+				nil,
+				s.opts.RewardTickets,
+			)
+			if err != nil {
+				return err
+			}
+
+			numbers := make([]string, 0, len(tickets))
+			for _, ticket := range tickets {
+				numbers = append(numbers, ticket.Number)
+			}
+			intNumbers, err := utils.ConvertToIntSlice(numbers)
+			if err != nil {
+				return err
+			}
+			return s.repo.SetRewardTickets(ctx, tx, reward.ID, intNumbers)
+		},
+	)
+	return err
+}
+
+func (s *service) GetSummary(
+	ctx context.Context,
+	userID string,
+) (form.ReferralSummary, error) {
+	return s.repo.GetSummary(ctx, userID)
+}
+
+func (s *service) GetTopReferrers(
+	ctx context.Context,
+	filters dto.GetTopReferrers,
+) ([]form.TopReferrer, int, error) {
+	return s.repo.GetTopReferrers(ctx, filters)
+}
@@ -0,0 +1,252 @@
+package refund
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/internal/core/audit"
+	"rifa/backend/internal/core/email"
+	"rifa/backend/internal/core/purchase"
+	"rifa/backend/internal/events"
+	"rifa/backend/internal/repository"
+	"rifa/backend/internal/types"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+	"rifa/backend/pkg/utils"
+)
+
+var (
+	ErrNotFound         = errors.New("refund not found")
+	ErrPurchaseNotFound = errors.New("purchase not found")
+	ErrNotRefundable    = errors.New("purchase can't be fully refunded")
+	ErrExceedsPayment   = errors.New("refunds exceed the amount paid")
+	ErrCurrency         = errors.New("refund currency differs from the payment")
+	ErrAlreadyCompleted = errors.New("refund already completed")
+)
+
+type Service interface {
+	Create(
+		ctx context.Context,
+		actor types.Actor,
+		refund types.Refund,
+	) (types.Refund, error)
+	// Complete records the reference and proof of the money sent back, and
+	// moves the purchase of a full refund to refunded
+	Complete(
+		ctx context.Context,
+		actor types.Actor,
+		refundID,
+		reference string,
+		proof []byte,
+	) (types.Refund, error)
+	List(
+		ctx context.Context,
+		filters dto.GetRefunds,
+	) ([]types.Refund, int, error)
+}
+
+type service struct {
+	repo         repository.RefundRepository
+	purchaseRepo repository.PurchaseRepository
+	methodRepo   repository.PaymentMethodRepository
+	purchases    purchase.Service
+	audit        audit.Service
+}
+
+func NewService(
+	db database.DB,
+	emailClient email.Mailer,
+	recorder events.Recorder,
+	opts config.ServiceOpts,
+) Service {
+	return &service{
+		repo:         repository.NewRefundRepository(db),
+		purchaseRepo: repository.NewPurchaseRepository(db),
+		methodRepo:   repository.NewPaymentMethodRepository(db),
+		purchases:    purchase.NewService(db, emailClient, recorder, opts),
+		audit:        audit.NewService(db),
+	}
+}
+
+func (s *service) Create(
+	ctx context.Context,
+	actor types.Actor,
+	refund types.Refund,
+) (types.Refund, error) {
+	p, err := s.purchaseRepo.GetByID(ctx, refund.PurchaseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.Refund{}, ErrPurchaseNotFound
+	}
+	if err != nil {
+		return types.Refund{}, err
+	}
+	if refund.Full && !p.Status.CanTransitionTo(types.StatusRefunded) {
+		return types.Refund{}, ErrNotRefundable
+	}
+
+	// Purchases carry both amounts but only the one in the currency of the
+	// payment method was paid. When the method is gone, the requested
+	// currency is trusted.
+	method, err := s.methodRepo.GetByCode(ctx, p.PaymentMethod)
+	switch {
+	case err == nil && method.Currency != refund.Currency:
+		return types.Refund{}, ErrCurrency
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return types.Refund{}, err
+	}
+
+	paid := p.MontoBs
+	if refund.Currency == types.CurrencyUSD {
+		paid = p.MontoUSD
+	}
+
+	if actor.ID != "" {
+		refund.CreatedBy = &actor.ID
+	}
+	refund.Status = types.RefundPending
+	err = s.repo.Create(
+		ctx,
+		&refund,
+		func(ctx context.Context, tx database.Tx) error {
+			refunded, err := s.repo.RefundedAmount(
+				ctx,
+				tx,
+				p.ID,
+				refund.Currency,
+			)
+			if err != nil {
+				return err
+			}
+			if math.Round((refunded+refund.Amount)*100) > math.Round(paid*100) {
+				return ErrExceedsPayment
+			}
+			return nil
+		},
+		s.audit.Hook(func() types.AuditEntry {
+			return entry(actor, types.AuditRefundCreated, refund.ID, nil, refund)
+		}),
+	)
+	if err != nil {
+		return types.Refund{}, err
+	}
+	return s.repo.GetByID(ctx, refund.ID)
+}
+
+func (s *service) Complete(
+	ctx context.Context,
+	actor types.Actor,
+	refundID,
+	reference string,
+	proof []byte,
+) (types.Refund, error) {
+	before, err := s.repo.GetByID(ctx, refundID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.Refund{}, ErrNotFound
+	}
+	if err != nil {
+		return types.Refund{}, err
+	}
+	if before.Status == types.RefundCompleted {
+		return types.Refund{}, ErrAlreadyCompleted
+	}
+
+	after := before
+	after.Reference = reference
+	if len(proof) > 0 {
+		after.Proof, err = utils.CompressToJPG(proof)
+		if err != nil {
+			return types.Refund{}, err
+		}
+	}
+	if actor.ID != "" {
+		after.CompletedBy = &actor.ID
+	}
+
+	if before.Full {
+		err = s.refundPurchase(ctx, actor, before)
+		if err != nil {
+			return types.Refund{}, err
+		}
+	}
+
+	// Keep the proof out of the audit log, it's stored with the refund
+	snapshot := after
+	snapshot.Proof, before.Proof = nil, nil
+	snapshot.Status = types.RefundCompleted
+	err = s.repo.Complete(
+		ctx,
+		after,
+		s.audit.Hook(func() types.AuditEntry {
+			return entry(
+				actor,
+				types.AuditRefundCompleted,
+				refundID,
+				before,
+				snapshot,
+			)
+		}),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.Refund{}, ErrAlreadyCompleted
+	}
+	if err != nil {
+		return types.Refund{}, err
+	}
+
+	return s.repo.GetByID(ctx, refundID)
+}
+
+func (s *service) List(
+	ctx context.Context,
+	filters dto.GetRefunds,
+) ([]types.Refund, int, error) {
+	return s.repo.List(ctx, filters)
+}
+
+// refundPurchase moves the purchase of a full refund to refunded, unless a
+// previous or concurrent attempt to complete the refund already did
+func (s *service) refundPurchase(
+	ctx context.Context,
+	actor types.Actor,
+	refund types.Refund,
+) error {
+	changes, err := s.purchases.BulkUpdateStatus(
+		ctx,
+		actor,
+		[]string{refund.PurchaseID},
+		types.StatusRefunded,
+		refund.Reason,
+	)
+	if err != nil {
+		return err
+	}
+	change := changes[0]
+	switch {
+	case change.Result == types.StatusChangeApplied:
+		return nil
+	case change.Result == types.StatusChangeIllegal &&
+		change.Purchase.Status == types.StatusRefunded:
+		return nil
+	}
+	return ErrNotRefundable
+}
+
+func entry(
+	actor types.Actor,
+	action types.AuditAction,
+	refundID string,
+	before,
+	after any,
+) types.AuditEntry {
+	return types.AuditEntry{
+		Actor:      actor,
+		Action:     action,
+		TargetType: types.AuditTargetRefund,
+		TargetID:   refundID,
+		Before:     before,
+		After:      after,
+	}
+}
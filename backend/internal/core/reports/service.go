@@ -0,0 +1,117 @@
+package reports
+
+import (
+	"context"
+	"time"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/api/httpx/form"
+	"rifa/backend/internal/repository"
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+type Service interface {
+	// GetRevenue reports the purchase amounts of a lottery, the active one
+	// when filters doesn't name any
+	GetRevenue(
+		ctx context.Context,
+		filters dto.GetRevenueReport,
+	) (form.RevenueReport, error)
+	// GetSalesVelocity reports how fast the tickets of the active lottery
+	// sell and projects when they'll run out
+	GetSalesVelocity(
+		ctx context.Context,
+		filters dto.GetSalesVelocity,
+	) (form.SalesVelocity, error)
+}
+
+type service struct {
+	repo       repository.ReportRepository
+	ticketRepo repository.TicketRepository
+}
+
+func NewService(db database.DB) Service {
+	return &service{
+		repo:       repository.NewReportRepository(db),
+		ticketRepo: repository.NewTicketRepository(db),
+	}
+}
+
+func (s *service) GetRevenue(
+	ctx context.Context,
+	filters dto.GetRevenueReport,
+) (form.RevenueReport, error) {
+	lotteryID, err := s.lotteryID(ctx, filters.LotteryID)
+	if err != nil {
+		return form.RevenueReport{}, err
+	}
+	return s.repo.GetRevenue(ctx, lotteryID, filters)
+}
+
+func (s *service) GetSalesVelocity(
+	ctx context.Context,
+	filters dto.GetSalesVelocity,
+) (form.SalesVelocity, error) {
+	lotteryID, err := s.ticketRepo.GetActiveLotteryID(ctx)
+	if err != nil {
+		return form.SalesVelocity{}, err
+	}
+
+	counts, err := s.repo.CountTickets(ctx, lotteryID)
+	if err != nil {
+		return form.SalesVelocity{}, err
+	}
+	buckets, err := s.repo.GetSales(ctx, lotteryID, filters.Interval)
+	if err != nil {
+		return form.SalesVelocity{}, err
+	}
+
+	tickets := make([]int, len(buckets))
+	for i, b := range buckets {
+		tickets[i] = b.Tickets()
+	}
+	averages := types.MovingAverage(tickets, filters.Window)
+
+	velocity := form.SalesVelocity{
+		LotteryID: lotteryID,
+		Interval:  filters.Interval,
+		Sold:      counts.Sold,
+		Reserved:  counts.Reserved,
+		Available: counts.Available,
+		Periods:   []form.SalesPeriod{},
+	}
+	if len(averages) > 0 {
+		velocity.Rate = averages[len(averages)-1]
+	}
+	interval := 24 * time.Hour
+	if filters.Interval == "hour" {
+		interval = time.Hour
+	}
+	velocity.ProjectedSellOut = types.SellOutAt(
+		time.Now(),
+		counts.Available,
+		velocity.Rate,
+		interval,
+	)
+
+	// The moving average needs every interval, only the latest are returned
+	first := max(len(buckets)-filters.Periods, 0)
+	for i := first; i < len(buckets); i++ {
+		velocity.Periods = append(velocity.Periods, form.SalesPeriod{
+			Period:        buckets[i].Period,
+			Sold:          buckets[i].Sold,
+			Reserved:      buckets[i].Reserved,
+			MovingAverage: averages[i],
+		})
+	}
+
+	return velocity, nil
+}
+
+func (s *service) lotteryID(ctx context.Context, id string) (string, error) {
+	if id != "" {
+		return id, nil
+	}
+	return s.ticketRepo.GetActiveLotteryID(ctx)
+}
@@ -0,0 +1,57 @@
+package search
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"rifa/backend/api/httpx/form"
+	"rifa/backend/internal/repository"
+	database "rifa/backend/pkg/db"
+)
+
+// idPrefix matches the start of a purchase id, long enough to be worth
+// looking up
+var idPrefix = regexp.MustCompile(`^[0-9a-f-]{8,36}$`)
+
+// maxTicketNumber is the highest number of a lottery
+const maxTicketNumber = 9999
+
+type Service interface {
+	// Search finds the users, purchases and active lottery tickets matching
+	// query, best matches first
+	Search(ctx context.Context, query string, limit int) ([]form.SearchHit, error)
+}
+
+type service struct {
+	repo repository.SearchRepository
+}
+
+func NewService(db database.DB) Service {
+	return &service{repo: repository.NewSearchRepository(db)}
+}
+
+func (s *service) Search(
+	ctx context.Context,
+	query string,
+	limit int,
+) ([]form.SearchHit, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return []form.SearchHit{}, nil
+	}
+
+	var prefix *string
+	if lower := strings.ToLower(query); idPrefix.MatchString(lower) {
+		pattern := lower + "%"
+		prefix = &pattern
+	}
+
+	var ticket *int
+	if n, err := strconv.Atoi(query); err == nil && n >= 0 && n <= maxTicketNumber {
+		ticket = &n
+	}
+
+	return s.repo.Search(ctx, query, prefix, ticket, limit)
+}
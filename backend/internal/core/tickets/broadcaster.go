@@ -0,0 +1,159 @@
+package ticket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"rifa/backend/api/httpx/form"
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+	"rifa/backend/pkg/utils"
+)
+
+// ticketsChannel is notified by the tickets table whenever tickets change
+const ticketsChannel = "tickets_changed"
+
+// reconnectDelay is how long Run waits before listening again after the
+// connection fails
+const reconnectDelay = 5 * time.Second
+
+// ticketsChange is the payload of a ticketsChannel notification. Taken and
+// Released are missing when too many tickets changed at once.
+type ticketsChange struct {
+	LotteryID string `json:"lotteryId"`
+	Taken     []int  `json:"taken"`
+	Released  []int  `json:"released"`
+}
+
+// Broadcaster shares the ticket updates of the active lottery with every
+// stream subscriber, querying the availability once per change no matter
+// how many clients are connected
+type Broadcaster struct {
+	srv Service
+
+	mu      sync.Mutex
+	clients map[chan form.TicketsUpdate]struct{}
+	// last is the latest availability, only kept while listening since it
+	// would go stale otherwise
+	last *form.TicketsPercentage
+}
+
+func NewBroadcaster(srv Service) *Broadcaster {
+	return &Broadcaster{
+		srv:     srv,
+		clients: map[chan form.TicketsUpdate]struct{}{},
+	}
+}
+
+// Run listens for ticket changes until ctx is done, reconnecting when the
+// connection fails
+func (b *Broadcaster) Run(ctx context.Context, listener database.Listener) {
+	for {
+		// Changes made before LISTEN takes effect are picked up by the
+		// next notification
+		if availability, err := b.srv.GetAvailability(ctx); err == nil {
+			current := percentage(availability)
+			b.setLast(&current)
+		}
+
+		err := listener.Listen(ctx, ticketsChannel, func(payload string) {
+			b.handle(ctx, payload)
+		})
+		b.setLast(nil)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("tickets listener stopped: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// Subscribe returns a channel receiving every update until unsubscribe is
+// called. Updates are dropped for subscribers too slow to take them.
+func (b *Broadcaster) Subscribe() (<-chan form.TicketsUpdate, func()) {
+	ch := make(chan form.TicketsUpdate, 8)
+
+	b.mu.Lock()
+	b.clients[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.clients, ch)
+		b.mu.Unlock()
+	}
+}
+
+// Snapshot returns the current availability of the active lottery
+func (b *Broadcaster) Snapshot(
+	ctx context.Context,
+) (form.TicketsPercentage, error) {
+	b.mu.Lock()
+	last := b.last
+	b.mu.Unlock()
+	if last != nil {
+		return *last, nil
+	}
+
+	availability, err := b.srv.GetAvailability(ctx)
+	if err != nil {
+		return form.TicketsPercentage{}, err
+	}
+	return percentage(availability), nil
+}
+
+func (b *Broadcaster) handle(ctx context.Context, payload string) {
+	var change ticketsChange
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		log.Printf("invalid tickets notification %q: %v", payload, err)
+		return
+	}
+
+	availability, err := b.srv.GetAvailability(ctx)
+	if err != nil {
+		log.Printf("failed to refresh tickets availability: %v", err)
+		b.setLast(nil)
+		return
+	}
+	current := percentage(availability)
+	b.setLast(&current)
+
+	update := form.TicketsUpdate{TicketsPercentage: current}
+	// Numbers of other lotteries, like refunds of past ones, mean nothing
+	// to the landing page
+	if change.LotteryID == availability.LotteryID {
+		update.Taken = utils.ConvertToStrSlice(change.Taken)
+		update.Released = utils.ConvertToStrSlice(change.Released)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.clients {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
+
+func (b *Broadcaster) setLast(last *form.TicketsPercentage) {
+	b.mu.Lock()
+	b.last = last
+	b.mu.Unlock()
+}
+
+func percentage(availability types.TicketAvailability) form.TicketsPercentage {
+	return form.TicketsPercentage{
+		Percentage: availability.Sold + availability.Reserved,
+		Sold:       availability.Sold,
+		Reserved:   availability.Reserved,
+	}
+}
@@ -18,7 +18,7 @@ type Service interface {
 		quantity int,
 	) ([]types.Ticket, error)
 	SearchTickets(ctx context.Context, tickets []int) ([]int, error)
-	GetAvailability(ctx context.Context) (float64, error)
+	GetAvailability(ctx context.Context) (types.TicketAvailability, error)
 	GetUserTickets(ctx context.Context, userID string) ([]int, error)
 }
 
@@ -61,12 +61,16 @@ func (s *service) SearchTickets(
 	return s.repo.GetUnavailableNumbers(ctx, lotteryID, tickets)
 }
 
-func (s *service) GetAvailability(ctx context.Context) (float64, error) {
+func (s *service) GetAvailability(
+	ctx context.Context,
+) (types.TicketAvailability, error) {
 	lotteryID, err := s.repo.GetActiveLotteryID(ctx)
 	if err != nil {
-		return 0, err
+		return types.TicketAvailability{}, err
 	}
-	return s.repo.GetAvailabilityPercentage(ctx, lotteryID)
+	availability, err := s.repo.GetAvailabilityPercentage(ctx, lotteryID)
+	availability.LotteryID = lotteryID
+	return availability, err
 }
 
 func (s *service) GetUserTickets(
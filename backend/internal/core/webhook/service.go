@@ -0,0 +1,459 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/internal/core/audit"
+	"rifa/backend/internal/events"
+	"rifa/backend/internal/repository"
+	"rifa/backend/internal/types"
+	"rifa/backend/pkg/config"
+	database "rifa/backend/pkg/db"
+	"rifa/backend/pkg/utils"
+	"rifa/backend/pkg/webhook"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	ErrNotFound = errors.New("webhook not found")
+	ErrInvalid  = errors.New("invalid webhook")
+)
+
+// TestEvent is the name of the events sent by SendTest, endpoints can't
+// subscribe to it
+const TestEvent = "webhook.test"
+
+const (
+	// retryBatch is how many due deliveries are claimed at once
+	retryBatch = 50
+	// retryWorkers is how many deliveries of a batch are sent concurrently
+	retryWorkers = 10
+)
+
+// Payload is the JSON body of every delivery. ID is the same on every
+// delivery of an event, so receivers can tell retries apart from new events.
+type Payload struct {
+	ID         string    `json:"id"`
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Data       any       `json:"data"`
+}
+
+type Service interface {
+	List(ctx context.Context) ([]types.WebhookEndpoint, error)
+	// Create stores the endpoint, generating its secret when it has none
+	Create(
+		ctx context.Context,
+		actor types.Actor,
+		endpoint types.WebhookEndpoint,
+	) (types.WebhookEndpoint, error)
+	// Update replaces the endpoint, keeping its secret when it has none
+	Update(
+		ctx context.Context,
+		actor types.Actor,
+		endpoint types.WebhookEndpoint,
+	) (types.WebhookEndpoint, error)
+	Delete(ctx context.Context, actor types.Actor, id string) error
+	ListDeliveries(
+		ctx context.Context,
+		filters dto.GetWebhookDeliveries,
+	) ([]types.WebhookDelivery, int, error)
+	// SendTest delivers a test event to the endpoint once, whether it's
+	// active or not, and returns the outcome
+	SendTest(ctx context.Context, id string) (types.WebhookDelivery, error)
+	// Handle stores a delivery of the event for every endpoint subscribed to
+	// it, failing when they couldn't be stored. The deliveries are sent by
+	// RetryDue. It's meant to be subscribed to the events bus as a durable
+	// subscriber.
+	Handle(ctx context.Context, msg events.Message) error
+	// Stored receives a value after Handle stores new deliveries
+	Stored() <-chan struct{}
+	// RetryDue sends the pending deliveries that are due, the new ones and
+	// the failed ones whose backoff is over
+	RetryDue(ctx context.Context) error
+}
+
+type service struct {
+	repo   repository.WebhookRepository
+	audit  audit.Service
+	client *webhook.Client
+	opts   config.WebhookOpts
+	stored chan struct{}
+}
+
+func NewService(db database.DB, opts config.ServiceOpts) Service {
+	return &service{
+		repo:  repository.NewWebhookRepository(db),
+		audit: audit.NewService(db),
+		// Endpoints are set by admins, but they mustn't be able to reach
+		// the internal network through them
+		client: webhook.NewClient(&http.Client{
+			Transport:     otelhttp.NewTransport(webhook.PublicTransport()),
+			Timeout:       opts.Webhook.Timeout,
+			CheckRedirect: webhook.NoRedirects,
+		}),
+		opts:   opts.Webhook,
+		stored: make(chan struct{}, 1),
+	}
+}
+
+// RunRetries calls RetryDue every interval, and as soon as srv stores new
+// deliveries, until ctx is done. A zero or negative interval disables the
+// periodic retries, new deliveries are still sent.
+func RunRetries(ctx context.Context, srv Service, interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		if err := srv.RetryDue(ctx); err != nil {
+			log.Printf("failed to retry webhook deliveries: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-srv.Stored():
+		}
+	}
+}
+
+func (s *service) List(ctx context.Context) ([]types.WebhookEndpoint, error) {
+	return s.repo.ListEndpoints(ctx)
+}
+
+func (s *service) Create(
+	ctx context.Context,
+	actor types.Actor,
+	endpoint types.WebhookEndpoint,
+) (types.WebhookEndpoint, error) {
+	if !validEndpoint(endpoint) {
+		return types.WebhookEndpoint{}, ErrInvalid
+	}
+
+	if endpoint.Secret == "" {
+		code, err := utils.RandomCode(32)
+		if err != nil {
+			return types.WebhookEndpoint{}, err
+		}
+		endpoint.Secret = "whsec_" + code
+	}
+
+	err := s.repo.CreateEndpoint(
+		ctx,
+		&endpoint,
+		s.audit.Hook(func() types.AuditEntry {
+			return entry(actor, types.AuditWebhookCreated, endpoint.ID, nil, endpoint)
+		}),
+	)
+	if err != nil {
+		return types.WebhookEndpoint{}, err
+	}
+	return endpoint, nil
+}
+
+func (s *service) Update(
+	ctx context.Context,
+	actor types.Actor,
+	endpoint types.WebhookEndpoint,
+) (types.WebhookEndpoint, error) {
+	if !validEndpoint(endpoint) {
+		return types.WebhookEndpoint{}, ErrInvalid
+	}
+
+	before, err := s.get(ctx, endpoint.ID)
+	if err != nil {
+		return types.WebhookEndpoint{}, err
+	}
+	if endpoint.Secret == "" {
+		endpoint.Secret = before.Secret
+	}
+	endpoint.CreatedAt = before.CreatedAt
+
+	err = s.repo.UpdateEndpoint(
+		ctx,
+		endpoint,
+		s.audit.Hook(func() types.AuditEntry {
+			return entry(actor, types.AuditWebhookUpdated, endpoint.ID, before, endpoint)
+		}),
+	)
+	if err != nil {
+		return types.WebhookEndpoint{}, err
+	}
+	return endpoint, nil
+}
+
+func (s *service) Delete(
+	ctx context.Context,
+	actor types.Actor,
+	id string,
+) error {
+	before, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.DeleteEndpoint(
+		ctx,
+		id,
+		s.audit.Hook(func() types.AuditEntry {
+			return entry(actor, types.AuditWebhookDeleted, id, before, nil)
+		}),
+	)
+}
+
+func (s *service) ListDeliveries(
+	ctx context.Context,
+	filters dto.GetWebhookDeliveries,
+) ([]types.WebhookDelivery, int, error) {
+	_, err := s.get(ctx, filters.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.repo.ListDeliveries(ctx, filters)
+}
+
+func (s *service) SendTest(
+	ctx context.Context,
+	id string,
+) (types.WebhookDelivery, error) {
+	endpoint, err := s.get(ctx, id)
+	if err != nil {
+		return types.WebhookDelivery{}, err
+	}
+
+	eventID := uuid.NewString()
+	payload, err := json.Marshal(Payload{
+		ID:         eventID,
+		Event:      TestEvent,
+		OccurredAt: time.Now(),
+		Data: map[string]string{
+			"endpointId": endpoint.ID,
+			"message":    "Evento de prueba de la rifa",
+		},
+	})
+	if err != nil {
+		return types.WebhookDelivery{}, err
+	}
+
+	deliveries, err := s.repo.CreateDeliveries(
+		ctx,
+		eventID,
+		TestEvent,
+		payload,
+		[]string{endpoint.ID},
+		s.lease(),
+	)
+	if err != nil {
+		return types.WebhookDelivery{}, err
+	}
+	if len(deliveries) == 0 {
+		return types.WebhookDelivery{}, errors.New("test delivery not created")
+	}
+
+	// Test events aren't retried, the admin sees the outcome right away
+	return s.deliver(ctx, endpoint, deliveries[0], 1)
+}
+
+func (s *service) Handle(ctx context.Context, msg events.Message) error {
+	name := msg.Event.Name()
+	endpoints, err := s.repo.ListSubscribed(ctx, name)
+	if err != nil || len(endpoints) == 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(Payload{
+		ID:         msg.ID,
+		Event:      name,
+		OccurredAt: msg.OccurredAt,
+		Data:       msg.Event,
+	})
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		ids = append(ids, endpoint.ID)
+	}
+
+	// The deliveries are due right away, so the bus isn't held by slow
+	// endpoints. The outbox may relay an event more than once, the
+	// deliveries already stored for it aren't stored again.
+	deliveries, err := s.repo.CreateDeliveries(ctx, msg.ID, name, payload, ids, 0)
+	if err != nil {
+		return err
+	}
+
+	if len(deliveries) > 0 {
+		select {
+		case s.stored <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *service) Stored() <-chan struct{} {
+	return s.stored
+}
+
+func (s *service) RetryDue(ctx context.Context) error {
+	deliveries, err := s.repo.ClaimDue(ctx, retryBatch, s.lease())
+	if err != nil {
+		return err
+	}
+
+	endpoints := map[string]types.WebhookEndpoint{}
+	for _, delivery := range deliveries {
+		if _, ok := endpoints[delivery.EndpointID]; ok {
+			continue
+		}
+		endpoint, err := s.repo.GetEndpoint(ctx, delivery.EndpointID)
+		if err != nil {
+			return err
+		}
+		endpoints[endpoint.ID] = endpoint
+	}
+
+	var group errgroup.Group
+	group.SetLimit(retryWorkers)
+	for _, delivery := range deliveries {
+		endpoint := endpoints[delivery.EndpointID]
+		group.Go(func() error {
+			if !endpoint.Active {
+				return s.giveUp(ctx, delivery, "webhook disabled")
+			}
+			_, err := s.deliver(ctx, endpoint, delivery, s.opts.MaxAttempts)
+			return err
+		})
+	}
+	return group.Wait()
+}
+
+// deliver sends the delivery to the endpoint and saves the outcome. Failed
+// deliveries are scheduled again after a backoff until maxAttempts is
+// reached.
+func (s *service) deliver(
+	ctx context.Context,
+	endpoint types.WebhookEndpoint,
+	delivery types.WebhookDelivery,
+	maxAttempts int,
+) (types.WebhookDelivery, error) {
+	resp, sendErr := s.client.Send(ctx, webhook.Request{
+		URL:        endpoint.URL,
+		Secret:     endpoint.Secret,
+		Event:      delivery.Event,
+		DeliveryID: delivery.ID,
+		Body:       delivery.Payload,
+	})
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = nil
+	delivery.ResponseBody = nil
+	delivery.Error = nil
+	delivery.NextAttemptAt = nil
+	if resp.StatusCode != 0 {
+		delivery.ResponseStatus = &resp.StatusCode
+		delivery.ResponseBody = &resp.Body
+	}
+
+	switch {
+	case sendErr == nil:
+		delivery.Status = types.WebhookSucceeded
+	case delivery.Attempts >= maxAttempts:
+		reason := sendErr.Error()
+		delivery.Error = &reason
+		delivery.Status = types.WebhookFailed
+	default:
+		reason := sendErr.Error()
+		delivery.Error = &reason
+		delivery.Status = types.WebhookPending
+		next := now.Add(webhook.Backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	return delivery, s.repo.SaveAttempt(ctx, delivery)
+}
+
+// giveUp marks the delivery failed without attempting it again
+func (s *service) giveUp(
+	ctx context.Context,
+	delivery types.WebhookDelivery,
+	reason string,
+) error {
+	delivery.Status = types.WebhookFailed
+	delivery.Error = &reason
+	delivery.NextAttemptAt = nil
+	return s.repo.SaveAttempt(ctx, delivery)
+}
+
+// lease is how long a delivery being attempted is kept from being retried,
+// long enough for the attempts of a claimed batch to time out first
+func (s *service) lease() time.Duration {
+	rounds := (retryBatch + retryWorkers - 1) / retryWorkers
+	return time.Duration(rounds)*s.opts.Timeout + time.Minute
+}
+
+func (s *service) get(
+	ctx context.Context,
+	id string,
+) (types.WebhookEndpoint, error) {
+	endpoint, err := s.repo.GetEndpoint(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.WebhookEndpoint{}, ErrNotFound
+	}
+	return endpoint, err
+}
+
+func entry(
+	actor types.Actor,
+	action types.AuditAction,
+	id string,
+	before,
+	after any,
+) types.AuditEntry {
+	return types.AuditEntry{
+		Actor:      actor,
+		Action:     action,
+		TargetType: types.AuditTargetWebhook,
+		TargetID:   id,
+		Before:     before,
+		After:      after,
+	}
+}
+
+func validEndpoint(endpoint types.WebhookEndpoint) bool {
+	u, err := url.Parse(endpoint.URL)
+	if err != nil || u.Host == "" ||
+		(u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	if len(endpoint.Events) == 0 {
+		return false
+	}
+	for _, name := range endpoint.Events {
+		if !events.Known(name) {
+			return false
+		}
+	}
+	return true
+}
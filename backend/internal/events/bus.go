@@ -0,0 +1,110 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// asyncBuffer is how many messages an async subscriber can fall behind before
+// Publish waits for it
+const asyncBuffer = 64
+
+// Message is an event along with the id and time it was recorded with
+type Message struct {
+	ID         string
+	OccurredAt time.Time
+	Event      Event
+}
+
+// Handler reacts to a published message
+type Handler func(ctx context.Context, msg Message)
+
+// DurableHandler reacts to a published message, failing when it couldn't
+// and the message must be published again
+type DurableHandler func(ctx context.Context, msg Message) error
+
+// Bus dispatches every published message to its subscribers, either on the
+// publisher's goroutine or on one of their own
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[int]Handler
+	durable  []DurableHandler
+	next     int
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: map[int]Handler{}}
+}
+
+// Publish hands msg to the durable subscribers and, once all of them
+// handled it, to the rest. It returns the errors of the durable subscribers,
+// in which case the message must be published again.
+func (b *Bus) Publish(ctx context.Context, msg Message) error {
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	durable := slices.Clone(b.durable)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range durable {
+		if err := handler(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for _, handler := range handlers {
+		handler(ctx, msg)
+	}
+	return nil
+}
+
+// Subscribe registers handler for every message published from now on. It
+// runs on the publisher's goroutine, so it must not block. The returned func
+// removes it.
+func (b *Bus) Subscribe(handler Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.next
+	b.next++
+	b.handlers[id] = handler
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// SubscribeDurable registers handler for the rest of the program, running it
+// on the publisher's goroutine. A message is only done once every durable
+// handler returned nil for it, so they see messages again after failing, or
+// after a crash, and must be idempotent.
+func (b *Bus) SubscribeDurable(handler DurableHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.durable = append(b.durable, handler)
+}
+
+// SubscribeAsync registers handler for the rest of the program, running it on
+// a goroutine of its own with the messages in publish order. It's meant for
+// slow, best effort side effects like emails: queued messages are lost if
+// the program stops before handling them.
+func (b *Bus) SubscribeAsync(handler Handler) {
+	queue := make(chan Message, asyncBuffer)
+	go func() {
+		for msg := range queue {
+			handler(context.Background(), msg)
+		}
+	}()
+	b.Subscribe(func(_ context.Context, msg Message) {
+		queue <- msg
+	})
+}
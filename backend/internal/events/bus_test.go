@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBus_PublishAndUnsubscribe(t *testing.T) {
+	bus := NewBus()
+	var first, second []string
+	unsubscribe := bus.Subscribe(func(_ context.Context, msg Message) {
+		first = append(first, msg.ID)
+	})
+	bus.Subscribe(func(_ context.Context, msg Message) {
+		second = append(second, msg.ID)
+	})
+
+	bus.Publish(context.Background(), Message{ID: "1", Event: PurchaseCreated{}})
+	unsubscribe()
+	bus.Publish(context.Background(), Message{ID: "2", Event: PriceChanged{}})
+
+	if len(first) != 1 || first[0] != "1" {
+		t.Errorf("first subscriber got %v, want [1]", first)
+	}
+	if len(second) != 2 || second[1] != "2" {
+		t.Errorf("second subscriber got %v, want [1 2]", second)
+	}
+}
+
+func TestBus_SubscribeAsyncKeepsOrder(t *testing.T) {
+	bus := NewBus()
+	received := make(chan string)
+	bus.SubscribeAsync(func(_ context.Context, msg Message) {
+		received <- msg.ID
+	})
+
+	// Publish returns before the handler runs as long as the queue has room
+	for _, id := range []string{"1", "2", "3"} {
+		bus.Publish(context.Background(), Message{ID: id, Event: PurchaseCreated{}})
+	}
+
+	for _, want := range []string{"1", "2", "3"} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("received %s, want %s", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+}
+
+func TestBus_DurableFailureHoldsBackOthers(t *testing.T) {
+	bus := NewBus()
+	fail := errors.New("store failed")
+	var durable, others int
+	bus.SubscribeDurable(func(context.Context, Message) error {
+		durable++
+		if durable == 1 {
+			return fail
+		}
+		return nil
+	})
+	bus.Subscribe(func(context.Context, Message) { others++ })
+
+	msg := Message{ID: "1", Event: PurchaseCreated{}}
+	if err := bus.Publish(context.Background(), msg); !errors.Is(err, fail) {
+		t.Fatalf("Publish() error = %v, want %v", err, fail)
+	}
+	if others != 0 {
+		t.Fatalf("others got %d messages before the durable handler succeeded", others)
+	}
+
+	if err := bus.Publish(context.Background(), msg); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if durable != 2 || others != 1 {
+		t.Errorf("durable = %d, others = %d, want 2 and 1", durable, others)
+	}
+}
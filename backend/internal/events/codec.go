@@ -0,0 +1,44 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownEvent = errors.New("unknown event")
+
+// decoders rebuilds every kind of event from its name
+var decoders = map[string]func(payload []byte) (Event, error){
+	NamePurchaseCreated:       decode[PurchaseCreated],
+	NamePurchaseVerified:      decode[PurchaseVerified],
+	NamePurchaseCancelled:     decode[PurchaseCancelled],
+	NamePurchaseStatusChanged: decode[PurchaseStatusChanged],
+	NamePriceChanged:          decode[PriceChanged],
+}
+
+func decode[T Event](payload []byte) (Event, error) {
+	var event T
+	err := json.Unmarshal(payload, &event)
+	return event, err
+}
+
+// Known reports whether name is the name of a kind of event
+func Known(name string) bool {
+	_, ok := decoders[name]
+	return ok
+}
+
+// Encode returns the JSON the event is stored and delivered as
+func Encode(event Event) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+// Decode rebuilds the event called name from its JSON
+func Decode(name string, payload []byte) (Event, error) {
+	decoder, ok := decoders[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEvent, name)
+	}
+	return decoder(payload)
+}
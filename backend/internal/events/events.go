@@ -0,0 +1,190 @@
+// Package events carries what happened in the services to the parts of the
+// app that react to it, without the services knowing who's listening
+package events
+
+import (
+	"time"
+
+	"rifa/backend/internal/types"
+)
+
+const (
+	NamePurchaseCreated       = "purchase.created"
+	NamePurchaseVerified      = "purchase.verified"
+	NamePurchaseCancelled     = "purchase.cancelled"
+	NamePurchaseStatusChanged = "purchase.status_changed"
+	NamePriceChanged          = "price.changed"
+)
+
+type Event interface {
+	// Name identifies the kind of event, e.g. "purchase.created"
+	Name() string
+}
+
+// Purchase is a purchase as carried by events, without its screenshot
+type Purchase struct {
+	ID                string               `json:"id"`
+	UserID            string               `json:"userId"`
+	LotteryID         string               `json:"lotteryId"`
+	Quantity          int                  `json:"quantity"`
+	BonusTickets      int                  `json:"bonusTickets"`
+	MontoBs           float64              `json:"montoBs"`
+	MontoUSD          float64              `json:"montoUsd"`
+	PaymentMethod     string               `json:"paymentMethod"`
+	TransactionDigits string               `json:"transactionDigits"`
+	Status            types.PurchaseStatus `json:"status"`
+	DuplicateOf       *string              `json:"duplicateOf,omitempty"`
+	AmountMismatch    bool                 `json:"amountMismatch"`
+	CreatedAt         time.Time            `json:"createdAt"`
+}
+
+func NewPurchase(p types.Purchase) Purchase {
+	return Purchase{
+		ID:                p.ID,
+		UserID:            p.UserID,
+		LotteryID:         p.LotteryID,
+		Quantity:          p.Quantity,
+		BonusTickets:      p.BonusTickets,
+		MontoBs:           p.MontoBs,
+		MontoUSD:          p.MontoUSD,
+		PaymentMethod:     p.PaymentMethod,
+		TransactionDigits: p.TransactionDigits,
+		Status:            p.Status,
+		DuplicateOf:       p.DuplicateOf,
+		AmountMismatch:    p.AmountMismatch,
+		CreatedAt:         p.CreatedAt,
+	}
+}
+
+func (p Purchase) toPurchase() types.Purchase {
+	return types.Purchase{
+		ID:                p.ID,
+		UserID:            p.UserID,
+		LotteryID:         p.LotteryID,
+		Quantity:          p.Quantity,
+		BonusTickets:      p.BonusTickets,
+		MontoBs:           p.MontoBs,
+		MontoUSD:          p.MontoUSD,
+		PaymentMethod:     p.PaymentMethod,
+		TransactionDigits: p.TransactionDigits,
+		Status:            p.Status,
+		DuplicateOf:       p.DuplicateOf,
+		AmountMismatch:    p.AmountMismatch,
+		CreatedAt:         p.CreatedAt,
+	}
+}
+
+// PurchaseCreated is recorded once a purchase is stored and its tickets
+// reserved
+type PurchaseCreated struct {
+	Purchase Purchase `json:"purchase"`
+}
+
+func (PurchaseCreated) Name() string { return NamePurchaseCreated }
+
+// StatusChange describes a purchase moved to another status, Purchase holds
+// the new one
+type StatusChange struct {
+	Purchase   Purchase             `json:"purchase"`
+	BuyerEmail string               `json:"buyerEmail"`
+	From       types.PurchaseStatus `json:"from"`
+	Reason     string               `json:"reason,omitempty"`
+	Actor      types.Actor          `json:"actor"`
+}
+
+// StatusEvent is implemented by every purchase status change event
+type StatusEvent interface {
+	Event
+	Change() StatusChange
+}
+
+// Change returns the status change e carries, so the purchase events can be
+// handled alike
+func (e StatusChange) Change() StatusChange { return e }
+
+type PurchaseVerified struct {
+	StatusChange
+}
+
+func (PurchaseVerified) Name() string { return NamePurchaseVerified }
+
+type PurchaseCancelled struct {
+	StatusChange
+	// Expired is set when the purchase was cancelled for staying pending too
+	// long rather than by an admin
+	Expired bool `json:"expired"`
+}
+
+func (PurchaseCancelled) Name() string { return NamePurchaseCancelled }
+
+// PurchaseStatusChanged covers the transitions without an event of their
+// own, like refunds or verified purchases sent back to pending
+type PurchaseStatusChanged struct {
+	StatusChange
+}
+
+func (PurchaseStatusChanged) Name() string { return NamePurchaseStatusChanged }
+
+// NewStatusEvent returns the event for a purchase moved from change to
+// status
+func NewStatusEvent(
+	change types.StatusChange,
+	status types.PurchaseStatus,
+	reason string,
+	actor types.Actor,
+) Event {
+	purchase := NewPurchase(*change.Purchase)
+	purchase.Status = status
+	sc := StatusChange{
+		Purchase:   purchase,
+		BuyerEmail: change.BuyerEmail,
+		From:       change.Purchase.Status,
+		Reason:     reason,
+		Actor:      actor,
+	}
+	switch status {
+	case types.StatusVerified:
+		return PurchaseVerified{sc}
+	case types.StatusCancelled:
+		return PurchaseCancelled{StatusChange: sc}
+	}
+	return PurchaseStatusChanged{sc}
+}
+
+// NewExpiredEvent returns the event for a pending purchase cancelled by the
+// expiry job
+func NewExpiredEvent(change types.StatusChange, reason string) Event {
+	event := NewStatusEvent(change, types.StatusCancelled, reason, types.Actor{})
+	cancelled := event.(PurchaseCancelled)
+	cancelled.Expired = true
+	return cancelled
+}
+
+// PriceChangeCause tells what changed the prices of a lottery
+type PriceChangeCause string
+
+const (
+	// PriceCauseUpdate is new unit prices in effect right away
+	PriceCauseUpdate PriceChangeCause = "prices"
+	// PriceCauseScheduled is unit prices scheduled earlier coming into effect
+	PriceCauseScheduled PriceChangeCause = "scheduled"
+	// PriceCauseTiers is a new set of bundles
+	PriceCauseTiers PriceChangeCause = "tiers"
+	// PriceCauseRate is a new exchange rate moving the Bs prices derived
+	// from the USD ones
+	PriceCauseRate PriceChangeCause = "rate"
+)
+
+// PriceChanged is recorded when the prices of a lottery change, for any of
+// the causes above. Prices are the unit prices in effect after the change,
+// Previous the ones before it, nil for the first prices of a lottery. Tiers
+// are only set when the bundles changed.
+type PriceChanged struct {
+	Cause    PriceChangeCause  `json:"cause"`
+	Prices   types.Prices      `json:"prices"`
+	Previous *types.Prices     `json:"previous,omitempty"`
+	Tiers    []types.PriceTier `json:"tiers,omitempty"`
+	Actor    types.Actor       `json:"actor"`
+}
+
+func (PriceChanged) Name() string { return NamePriceChanged }
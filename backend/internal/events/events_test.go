@@ -0,0 +1,93 @@
+package events
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"rifa/backend/internal/types"
+)
+
+func statusChange(from types.PurchaseStatus) types.StatusChange {
+	return types.StatusChange{
+		PurchaseID: "p1",
+		Result:     types.StatusChangeApplied,
+		Purchase:   &types.Purchase{ID: "p1", UserID: "u1", Status: from},
+		BuyerEmail: "ana@example.com",
+	}
+}
+
+func TestNewStatusEvent(t *testing.T) {
+	actor := types.Actor{ID: "admin"}
+	tests := []struct {
+		from, to types.PurchaseStatus
+		want     string
+	}{
+		{types.StatusPending, types.StatusVerified, NamePurchaseVerified},
+		{types.StatusPending, types.StatusCancelled, NamePurchaseCancelled},
+		{types.StatusVerified, types.StatusRefunded, NamePurchaseStatusChanged},
+		{types.StatusVerified, types.StatusPending, NamePurchaseStatusChanged},
+	}
+	for _, tt := range tests {
+		event := NewStatusEvent(statusChange(tt.from), tt.to, "", actor)
+		if event.Name() != tt.want {
+			t.Errorf("%s -> %s: got %s, want %s", tt.from, tt.to, event.Name(), tt.want)
+		}
+		change := event.(StatusEvent).Change()
+		if change.From != tt.from || change.Purchase.Status != tt.to {
+			t.Errorf(
+				"%s -> %s: change goes from %s to %s",
+				tt.from,
+				tt.to,
+				change.From,
+				change.Purchase.Status,
+			)
+		}
+	}
+}
+
+func TestNewExpiredEvent(t *testing.T) {
+	event := NewExpiredEvent(statusChange(types.StatusPending), "late")
+	cancelled, ok := event.(PurchaseCancelled)
+	if !ok || !cancelled.Expired {
+		t.Fatalf("NewExpiredEvent() = %#v, want an expired PurchaseCancelled", event)
+	}
+	if cancelled.Actor != (types.Actor{}) {
+		t.Errorf("expired purchase has actor %+v", cancelled.Actor)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	previous := types.Prices{ID: "pr0", BsAmount: 40, UsdAmount: 1}
+	tests := []Event{
+		PurchaseCreated{Purchase: Purchase{ID: "p1", Quantity: 3}},
+		NewStatusEvent(
+			statusChange(types.StatusPending),
+			types.StatusVerified,
+			"",
+			types.Actor{ID: "a"},
+		),
+		NewExpiredEvent(statusChange(types.StatusPending), "late"),
+		PriceChanged{Prices: types.Prices{ID: "pr1", BsAmount: 50}, Previous: &previous},
+	}
+	for _, want := range tests {
+		payload, err := Encode(want)
+		if err != nil {
+			t.Fatalf("Encode(%s) error = %v", want.Name(), err)
+		}
+		got, err := Decode(want.Name(), payload)
+		if err != nil {
+			t.Fatalf("Decode(%s) error = %v", want.Name(), err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode(%s) = %#v, want %#v", want.Name(), got, want)
+		}
+	}
+}
+
+func TestDecode_Unknown(t *testing.T) {
+	_, err := Decode("lottery.cancelled", []byte(`{}`))
+	if !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("Decode() error = %v, want ErrUnknownEvent", err)
+	}
+}
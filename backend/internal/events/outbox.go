@@ -0,0 +1,194 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"rifa/backend/internal/repository"
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+// outboxChannel is notified by the event_outbox table when events are
+// committed
+const outboxChannel = "event_outbox"
+
+const (
+	// relayBatch is how many events are claimed at once
+	relayBatch = 100
+	// relayLease is how long claimed events wait before being claimed again
+	// when the relay dies before dispatching them
+	relayLease = time.Minute
+	// relayMaxAttempts is how many times an event is published before it's
+	// set aside when durable subscribers keep failing to handle it
+	relayMaxAttempts = 10
+	// relayRetention is how long dispatched events are kept
+	relayRetention = 7 * 24 * time.Hour
+	// reconnectDelay is how long Run waits before listening again after the
+	// connection fails
+	reconnectDelay = 5 * time.Second
+)
+
+// Recorder stores events as part of a transaction, they're dispatched only
+// once it commits
+type Recorder interface {
+	Record(ctx context.Context, tx database.Tx, events ...Event) error
+}
+
+// Discard drops every event, for services built only to read
+var Discard Recorder = discard{}
+
+type discard struct{}
+
+func (discard) Record(context.Context, database.Tx, ...Event) error { return nil }
+
+// Outbox is the Recorder backed by the event_outbox table
+type Outbox struct {
+	repo repository.OutboxRepository
+}
+
+func NewOutbox(db database.DB) *Outbox {
+	return &Outbox{repo: repository.NewOutboxRepository(db)}
+}
+
+func (o *Outbox) Record(
+	ctx context.Context,
+	tx database.Tx,
+	events ...Event,
+) error {
+	messages := make([]types.OutboxMessage, 0, len(events))
+	for _, event := range events {
+		payload, err := Encode(event)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, types.OutboxMessage{
+			Name:    event.Name(),
+			Payload: payload,
+		})
+	}
+	return o.repo.Add(ctx, tx, messages...)
+}
+
+// Run publishes the recorded events to bus every interval until ctx is done,
+// and right after they're committed when listener isn't nil. Events are
+// published at least once: an event is only marked dispatched after the
+// durable subscribers handled it, the ones they failed to handle or that
+// were published right before a crash are published again. A zero or
+// negative interval polls every second.
+func (o *Outbox) Run(
+	ctx context.Context,
+	bus *Bus,
+	interval time.Duration,
+	listener database.Listener,
+) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	wake := make(chan struct{}, 1)
+	if listener != nil {
+		go o.listen(ctx, listener, wake)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var pruned time.Time
+	for {
+		for {
+			n, err := o.relay(ctx, bus)
+			if err != nil {
+				log.Printf("failed to relay events: %v", err)
+			}
+			if err != nil || n < relayBatch {
+				break
+			}
+		}
+
+		if time.Since(pruned) > time.Hour {
+			err := o.repo.Prune(ctx, time.Now().Add(-relayRetention))
+			if err != nil {
+				log.Printf("failed to prune dispatched events: %v", err)
+			}
+			pruned = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// listen signals wake on every outbox notification until ctx is done,
+// reconnecting when the connection fails
+func (o *Outbox) listen(
+	ctx context.Context,
+	listener database.Listener,
+	wake chan<- struct{},
+) {
+	for {
+		err := listener.Listen(ctx, outboxChannel, func(string) {
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("event outbox listener stopped: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// relay publishes one batch of pending events and returns its size
+func (o *Outbox) relay(ctx context.Context, bus *Bus) (int, error) {
+	messages, err := o.repo.Claim(ctx, relayBatch, relayLease)
+	if err != nil {
+		return 0, err
+	}
+
+	dispatched := make([]string, 0, len(messages))
+	for _, m := range messages {
+		event, err := Decode(m.Name, m.Payload)
+		if err != nil {
+			log.Printf("failed to decode event %s: %v", m.ID, err)
+			err = o.repo.MarkFailed(ctx, m.ID, err.Error())
+			if err != nil {
+				log.Printf("failed to set aside event %s: %v", m.ID, err)
+			}
+			continue
+		}
+		err = bus.Publish(ctx, Message{
+			ID:         m.ID,
+			OccurredAt: m.CreatedAt,
+			Event:      event,
+		})
+		if err != nil {
+			// Left claimed, the event is published again once its lease
+			// runs out
+			log.Printf("failed to handle event %s: %v", m.ID, err)
+			if m.Attempts >= relayMaxAttempts {
+				err = o.repo.MarkFailed(ctx, m.ID, err.Error())
+				if err != nil {
+					log.Printf("failed to set aside event %s: %v", m.ID, err)
+				}
+			}
+			continue
+		}
+		dispatched = append(dispatched, m.ID)
+	}
+
+	if len(dispatched) > 0 {
+		err = o.repo.MarkDispatched(ctx, dispatched)
+	}
+	return len(messages), err
+}
@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"rifa/backend/internal/core/email"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// EmailSubscriber sends the confirmation of every new purchase and lets
+// buyers know when theirs changes status
+func EmailSubscriber(mailer email.Mailer) Handler {
+	return func(_ context.Context, msg Message) {
+		var err error
+		switch e := msg.Event.(type) {
+		case PurchaseCreated:
+			err = mailer.SendPurchaseConfirmation(e.Purchase.toPurchase())
+		case StatusEvent:
+			change := e.Change()
+			err = mailer.SendPurchaseStatusUpdate(
+				change.BuyerEmail,
+				change.Purchase.toPurchase(),
+				change.Reason,
+			)
+		}
+		if err != nil {
+			log.Printf("failed to email %s event %s: %v", msg.Event.Name(), msg.ID, err)
+		}
+	}
+}
+
+// MetricsSubscriber counts the events by name and measures how long they
+// took to be dispatched since they were recorded
+func MetricsSubscriber(meter metric.Meter) (Handler, error) {
+	count, err := meter.Int64Counter(
+		"rifa.events",
+		metric.WithDescription("Domain events dispatched"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	lag, err := meter.Float64Histogram(
+		"rifa.events.lag",
+		metric.WithDescription("Time from recording an event to dispatching it"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, msg Message) {
+		name := metric.WithAttributes(attribute.String("event", msg.Event.Name()))
+		count.Add(ctx, 1, name)
+		lag.Record(ctx, time.Since(msg.OccurredAt).Seconds(), name)
+	}, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/api/httpx/form"
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+type AuditRepository interface {
+	// Create writes the entry in tx, the transaction of the change it
+	// describes
+	Create(ctx context.Context, tx database.Tx, entry types.AuditEntry) error
+	List(
+		ctx context.Context,
+		filters dto.GetAuditLog,
+	) ([]form.AuditEntry, int, error)
+}
+
+type auditRepo struct{ db database.DB }
+
+func NewAuditRepository(db database.DB) AuditRepository {
+	return &auditRepo{db: db}
+}
+
+func (r *auditRepo) Create(
+	ctx context.Context,
+	tx database.Tx,
+	entry types.AuditEntry,
+) error {
+	before, err := marshalSnapshot(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, err := marshalSnapshot(entry.After)
+	if err != nil {
+		return err
+	}
+
+	return tx.ExecContext(
+		ctx,
+		`INSERT INTO audit_log
+		(actor_id, action, target_type, target_id, before, after, ip, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		nullIfEmpty(entry.Actor.ID),
+		entry.Action,
+		entry.TargetType,
+		entry.TargetID,
+		before,
+		after,
+		nullIfEmpty(entry.Actor.IP),
+		nullIfEmpty(entry.Actor.RequestID),
+	)
+}
+
+func (r *auditRepo) List(
+	ctx context.Context,
+	filters dto.GetAuditLog,
+) ([]form.AuditEntry, int, error) {
+	var (
+		args       []any
+		conditions []string
+	)
+	addCondition := func(cond string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filters.ActorID != "" {
+		addCondition("actor_id = $%d", filters.ActorID)
+	}
+	if filters.TargetType != "" {
+		addCondition("target_type = $%d", filters.TargetType)
+	}
+	if filters.TargetID != "" {
+		addCondition("target_id = $%d", filters.TargetID)
+	}
+	if !filters.From.IsZero() {
+		addCondition("created_at >= $%d", filters.From)
+	}
+	if !filters.To.IsZero() {
+		addCondition("created_at < $%d", filters.To)
+	}
+
+	query := `SELECT id, actor_id, action, target_type, target_id,
+		before, after, ip, request_id, created_at,
+		COUNT(*) OVER() AS total_count
+	FROM audit_log `
+	query += whereClause(conditions)
+
+	perPage := filters.ItemCount
+	if perPage <= 0 {
+		perPage = 10
+	}
+	page := filters.Page
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * perPage
+	query += fmt.Sprintf(
+		"ORDER BY created_at DESC LIMIT $%d OFFSET $%d",
+		len(args)+1,
+		len(args)+2,
+	)
+	args = append(args, perPage, offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []form.AuditEntry{}
+	var total int
+	for rows.Next() {
+		var (
+			entry         form.AuditEntry
+			before, after []byte
+		)
+		err := rows.Scan(
+			&entry.ID,
+			&entry.ActorID,
+			&entry.Action,
+			&entry.TargetType,
+			&entry.TargetID,
+			&before,
+			&after,
+			&entry.IP,
+			&entry.RequestID,
+			&entry.CreatedAt,
+			&total,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		entry.Before = before
+		entry.After = after
+		entries = append(entries, entry)
+	}
+	if rows.Err() != nil {
+		return nil, 0, rows.Err()
+	}
+
+	return entries, total, nil
+}
+
+func marshalSnapshot(v any) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+type execCall struct {
+	query string
+	args  []any
+}
+
+type fakeTx struct {
+	execs      []execCall
+	execErr    error
+	committed  bool
+	rolledBack bool
+}
+
+func (f *fakeTx) Query(context.Context, string, ...any) (database.Rows, error) {
+	return nil, nil
+}
+func (f *fakeTx) QueryRow(context.Context, string, ...any) database.Row { return nil }
+func (f *fakeTx) ExecContext(_ context.Context, query string, args ...any) error {
+	f.execs = append(f.execs, execCall{query: query, args: args})
+	return f.execErr
+}
+func (f *fakeTx) Commit(context.Context) error   { f.committed = true; return nil }
+func (f *fakeTx) Rollback(context.Context) error { f.rolledBack = true; return nil }
+
+type fakeDB struct {
+	tx *fakeTx
+}
+
+func (f *fakeDB) Query(context.Context, string, ...any) (database.Rows, error) {
+	return nil, nil
+}
+func (f *fakeDB) QueryRow(context.Context, string, ...any) database.Row { return nil }
+func (f *fakeDB) ExecContext(context.Context, string, ...any) error {
+	return errors.New("unexpected write outside a transaction")
+}
+func (f *fakeDB) BeginTx(context.Context) (database.Tx, error) { return f.tx, nil }
+func (f *fakeDB) Close()                                       {}
+
+func TestAuditCreate_WritesInTx(t *testing.T) {
+	tx := &fakeTx{}
+	repo := NewAuditRepository(&fakeDB{})
+
+	err := repo.Create(context.Background(), tx, types.AuditEntry{
+		Actor:      types.Actor{ID: "admin", IP: "10.0.0.1"},
+		Action:     types.AuditCouponUpdated,
+		TargetType: types.AuditTargetCoupon,
+		TargetID:   "PROMO",
+		Before:     map[string]int{"value": 10},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(tx.execs) != 1 {
+		t.Fatalf("execs = %d, want 1", len(tx.execs))
+	}
+
+	args := tx.execs[0].args
+	if id := args[0].(*string); id == nil || *id != "admin" {
+		t.Errorf("actor = %v, want admin", args[0])
+	}
+	if got := string(args[4].([]byte)); got != `{"value":10}` {
+		t.Errorf("before = %s, want {\"value\":10}", got)
+	}
+	if args[5].([]byte) != nil {
+		t.Errorf("after = %s, want NULL", args[5])
+	}
+	if requestID := args[7].(*string); requestID != nil {
+		t.Errorf("request id = %v, want NULL", *requestID)
+	}
+}
+
+func TestInTx_CommitsAfterHooks(t *testing.T) {
+	tx := &fakeTx{}
+	var order []string
+
+	err := inTx(
+		context.Background(),
+		&fakeDB{tx: tx},
+		func(database.Tx) error {
+			order = append(order, "change")
+			return nil
+		},
+		[]TxHook{func(context.Context, database.Tx) error {
+			order = append(order, "hook")
+			return nil
+		}},
+	)
+	if err != nil {
+		t.Fatalf("inTx() error = %v", err)
+	}
+	if len(order) != 2 || order[0] != "change" || order[1] != "hook" {
+		t.Errorf("order = %v, want [change hook]", order)
+	}
+	if !tx.committed || tx.rolledBack {
+		t.Errorf("committed = %v, rolledBack = %v", tx.committed, tx.rolledBack)
+	}
+}
+
+func TestInTx_HookErrorRollsBack(t *testing.T) {
+	tx := &fakeTx{}
+	hookErr := errors.New("audit failed")
+
+	err := inTx(
+		context.Background(),
+		&fakeDB{tx: tx},
+		func(database.Tx) error { return nil },
+		[]TxHook{func(context.Context, database.Tx) error { return hookErr }},
+	)
+	if !errors.Is(err, hookErr) {
+		t.Fatalf("inTx() error = %v, want %v", err, hookErr)
+	}
+	if tx.committed || !tx.rolledBack {
+		t.Errorf("committed = %v, rolledBack = %v", tx.committed, tx.rolledBack)
+	}
+}
@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+type CouponRepository interface {
+	List(ctx context.Context) ([]types.Coupon, error)
+	GetByCode(ctx context.Context, code string) (types.Coupon, error)
+	// Create stores the coupon and sets its id, hooks run in the same
+	// transaction
+	Create(ctx context.Context, coupon *types.Coupon, hooks ...TxHook) error
+	Update(ctx context.Context, coupon types.Coupon, hooks ...TxHook) error
+	CountUserRedemptions(ctx context.Context, couponID, userID string) (int, error)
+	// Redeem ties the coupon to the purchase within tx unless that would
+	// exceed its max uses or per user limit, reporting whether it was redeemed
+	Redeem(
+		ctx context.Context,
+		tx database.Tx,
+		couponID,
+		userID,
+		purchaseID string,
+	) (bool, error)
+}
+
+type couponRepo struct{ db database.DB }
+
+func NewCouponRepository(db database.DB) CouponRepository {
+	return &couponRepo{db: db}
+}
+
+const couponColumns = `c.id, c.code, c.type, c.value, c.max_uses,
+	c.per_user_limit, c.starts_at, c.ends_at, c.lottery_id, c.enabled,
+	(SELECT COUNT(*) FROM coupon_redemptions r
+	WHERE r.coupon_id = c.id AND r.released_at IS NULL)`
+
+func (r *couponRepo) List(ctx context.Context) ([]types.Coupon, error) {
+	rows, err := r.db.Query(
+		ctx,
+		`SELECT `+couponColumns+` FROM coupons c ORDER BY c.created_at DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	coupons := []types.Coupon{}
+	for rows.Next() {
+		coupon, err := scanCoupon(rows)
+		if err != nil {
+			return nil, err
+		}
+		coupons = append(coupons, coupon)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return coupons, nil
+}
+
+func (r *couponRepo) GetByCode(
+	ctx context.Context,
+	code string,
+) (types.Coupon, error) {
+	row := r.db.QueryRow(
+		ctx,
+		`SELECT `+couponColumns+`
+		FROM coupons c
+		WHERE c.code = UPPER($1)`,
+		code,
+	)
+	return scanCoupon(row)
+}
+
+func (r *couponRepo) Create(
+	ctx context.Context,
+	coupon *types.Coupon,
+	hooks ...TxHook,
+) error {
+	const query = `
+	INSERT INTO coupons
+	(code, type, value, max_uses, per_user_limit, starts_at, ends_at,
+	lottery_id, enabled)
+	VALUES (UPPER($1), $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id
+	`
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		return tx.QueryRow(
+			ctx,
+			query,
+			coupon.Code,
+			coupon.Type,
+			coupon.Value,
+			coupon.MaxUses,
+			coupon.PerUserLimit,
+			coupon.StartsAt,
+			coupon.EndsAt,
+			coupon.LotteryID,
+			coupon.Enabled,
+		).Scan(&coupon.ID)
+	}, hooks)
+}
+
+func (r *couponRepo) Update(
+	ctx context.Context,
+	coupon types.Coupon,
+	hooks ...TxHook,
+) error {
+	const query = `
+	UPDATE coupons
+	SET type = $2,
+		value = $3,
+		max_uses = $4,
+		per_user_limit = $5,
+		starts_at = $6,
+		ends_at = $7,
+		lottery_id = $8,
+		enabled = $9,
+		updated_at = NOW()
+	WHERE code = UPPER($1)
+	`
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		return tx.ExecContext(
+			ctx,
+			query,
+			coupon.Code,
+			coupon.Type,
+			coupon.Value,
+			coupon.MaxUses,
+			coupon.PerUserLimit,
+			coupon.StartsAt,
+			coupon.EndsAt,
+			coupon.LotteryID,
+			coupon.Enabled,
+		)
+	}, hooks)
+}
+
+func (r *couponRepo) CountUserRedemptions(
+	ctx context.Context,
+	couponID,
+	userID string,
+) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		ctx,
+		`SELECT COUNT(*)
+		FROM coupon_redemptions
+		WHERE coupon_id = $1 AND user_id = $2 AND released_at IS NULL`,
+		couponID,
+		userID,
+	).Scan(&count)
+	return count, err
+}
+
+func (r *couponRepo) Redeem(
+	ctx context.Context,
+	tx database.Tx,
+	couponID,
+	userID,
+	purchaseID string,
+) (bool, error) {
+	// Locking the coupon serializes concurrent redemptions so the limits
+	// can't be overrun between the count and the insert
+	var maxUses, perUserLimit *int
+	err := tx.QueryRow(
+		ctx,
+		`SELECT max_uses, per_user_limit FROM coupons WHERE id = $1 FOR UPDATE`,
+		couponID,
+	).Scan(&maxUses, &perUserLimit)
+	if err != nil {
+		return false, err
+	}
+
+	var uses, userUses int
+	err = tx.QueryRow(
+		ctx,
+		`SELECT COUNT(*), COUNT(*) FILTER (WHERE user_id = $2)
+		FROM coupon_redemptions
+		WHERE coupon_id = $1 AND released_at IS NULL`,
+		couponID,
+		userID,
+	).Scan(&uses, &userUses)
+	if err != nil {
+		return false, err
+	}
+	if (maxUses != nil && uses >= *maxUses) ||
+		(perUserLimit != nil && userUses >= *perUserLimit) {
+		return false, nil
+	}
+
+	err = tx.ExecContext(
+		ctx,
+		`INSERT INTO coupon_redemptions (coupon_id, user_id, purchase_id)
+		VALUES ($1, $2, $3)`,
+		couponID,
+		userID,
+		purchaseID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func scanCoupon(row database.Row) (types.Coupon, error) {
+	var coupon types.Coupon
+	err := row.Scan(
+		&coupon.ID,
+		&coupon.Code,
+		&coupon.Type,
+		&coupon.Value,
+		&coupon.MaxUses,
+		&coupon.PerUserLimit,
+		&coupon.StartsAt,
+		&coupon.EndsAt,
+		&coupon.LotteryID,
+		&coupon.Enabled,
+		&coupon.Uses,
+	)
+	return coupon, err
+}
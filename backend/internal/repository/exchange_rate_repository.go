@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+type ExchangeRateRepository interface {
+	GetLatest(ctx context.Context) (types.ExchangeRate, error)
+	List(ctx context.Context, page, perPage int) ([]types.ExchangeRate, int, error)
+	// Save stores the rate and sets its id and date, hooks run in the same
+	// transaction
+	Save(ctx context.Context, rate *types.ExchangeRate, hooks ...TxHook) error
+}
+
+type exchangeRateRepo struct{ db database.DB }
+
+func NewExchangeRateRepository(db database.DB) ExchangeRateRepository {
+	return &exchangeRateRepo{db: db}
+}
+
+func (r *exchangeRateRepo) GetLatest(
+	ctx context.Context,
+) (types.ExchangeRate, error) {
+	const query = `
+	SELECT id, rate, source, created_by, created_at
+	FROM exchange_rates
+	ORDER BY created_at DESC
+	LIMIT 1
+	`
+	var rate types.ExchangeRate
+	err := r.db.QueryRow(ctx, query).Scan(
+		&rate.ID,
+		&rate.Rate,
+		&rate.Source,
+		&rate.CreatedBy,
+		&rate.CreatedAt,
+	)
+	return rate, err
+}
+
+func (r *exchangeRateRepo) List(
+	ctx context.Context,
+	page,
+	perPage int,
+) ([]types.ExchangeRate, int, error) {
+	if perPage <= 0 {
+		perPage = 10
+	}
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * perPage
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, rate, source, created_by, created_at,
+			COUNT(*) OVER() AS total_count
+		FROM exchange_rates
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2
+	`, perPage, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	rates := []types.ExchangeRate{}
+	var total int
+	for rows.Next() {
+		var rate types.ExchangeRate
+		err := rows.Scan(
+			&rate.ID,
+			&rate.Rate,
+			&rate.Source,
+			&rate.CreatedBy,
+			&rate.CreatedAt,
+			&total,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		rates = append(rates, rate)
+	}
+	if rows.Err() != nil {
+		return nil, 0, rows.Err()
+	}
+
+	return rates, total, nil
+}
+
+func (r *exchangeRateRepo) Save(
+	ctx context.Context,
+	rate *types.ExchangeRate,
+	hooks ...TxHook,
+) error {
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		return tx.QueryRow(
+			ctx,
+			`INSERT INTO exchange_rates (rate, source, created_by)
+			VALUES ($1, $2, $3)
+			RETURNING id, created_at`,
+			rate.Rate,
+			rate.Source,
+			rate.CreatedBy,
+		).Scan(&rate.ID, &rate.CreatedAt)
+	}, hooks)
+}
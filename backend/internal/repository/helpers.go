@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	database "rifa/backend/pkg/db"
+)
+
+// TxHook runs inside a repository transaction right before it's committed,
+// so what it writes is committed or rolled back along with the change
+type TxHook func(ctx context.Context, tx database.Tx) error
+
+// runHooks calls every hook with tx, stopping at the first error
+func runHooks(ctx context.Context, tx database.Tx, hooks []TxHook) error {
+	for _, hook := range hooks {
+		if err := hook(ctx, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inTx runs fn and then hooks in a single transaction, committing it only
+// when all of them succeed
+func inTx(
+	ctx context.Context,
+	db database.DB,
+	fn func(tx database.Tx) error,
+	hooks []TxHook,
+) (err error) {
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Printf("transaction rollback failed: %v", rbErr)
+			}
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+	err = runHooks(ctx, tx, hooks)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// whereClause joins the given conditions with AND, returning an empty string
+// when there is nothing to filter by
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ") + " "
+}
+
+// likePattern matches any text containing s, escaping the LIKE wildcards
+// in it
+func likePattern(s string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+	return "%" + escaped + "%"
+}
+
+// nullIfEmpty maps empty strings to SQL NULL
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+type LotteryRepository interface {
+	GetActive(ctx context.Context) (types.Lottery, error)
+	// UpdateLimits stores the limits of the lottery, hooks run in the same
+	// transaction
+	UpdateLimits(
+		ctx context.Context,
+		lottery types.Lottery,
+		hooks ...TxHook,
+	) error
+}
+
+type lotteryRepo struct{ db database.DB }
+
+func NewLotteryRepository(db database.DB) LotteryRepository {
+	return &lotteryRepo{db: db}
+}
+
+func (r *lotteryRepo) GetActive(ctx context.Context) (types.Lottery, error) {
+	var lottery types.Lottery
+	err := r.db.QueryRow(
+		ctx,
+		`SELECT id, name, active, min_per_purchase, max_per_purchase,
+		max_per_user, pending_expiry_hours, created_at
+		FROM lotteries
+		WHERE active = TRUE
+		LIMIT 1`,
+	).Scan(
+		&lottery.ID,
+		&lottery.Name,
+		&lottery.Active,
+		&lottery.MinPerPurchase,
+		&lottery.MaxPerPurchase,
+		&lottery.MaxPerUser,
+		&lottery.PendingExpiryHours,
+		&lottery.CreatedAt,
+	)
+	return lottery, err
+}
+
+func (r *lotteryRepo) UpdateLimits(
+	ctx context.Context,
+	lottery types.Lottery,
+	hooks ...TxHook,
+) error {
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		return tx.ExecContext(
+			ctx,
+			`UPDATE lotteries
+			SET min_per_purchase = $2,
+				max_per_purchase = $3,
+				max_per_user = $4,
+				pending_expiry_hours = $5
+			WHERE id = $1`,
+			lottery.ID,
+			lottery.MinPerPurchase,
+			lottery.MaxPerPurchase,
+			lottery.MaxPerUser,
+			lottery.PendingExpiryHours,
+		)
+	}, hooks)
+}
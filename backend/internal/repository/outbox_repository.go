@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+type OutboxRepository interface {
+	// Add stores messages as part of tx, they're only visible to Claim once
+	// tx commits
+	Add(ctx context.Context, tx database.Tx, messages ...types.OutboxMessage) error
+	// Claim locks up to limit undispatched messages for lease, oldest first.
+	// Messages whose lease ran out without being dispatched are claimed again.
+	Claim(
+		ctx context.Context,
+		limit int,
+		lease time.Duration,
+	) ([]types.OutboxMessage, error)
+	MarkDispatched(ctx context.Context, ids []string) error
+	// MarkFailed sets aside a message that can never be dispatched
+	MarkFailed(ctx context.Context, id, reason string) error
+	// Prune deletes the messages dispatched before the given time
+	Prune(ctx context.Context, before time.Time) error
+}
+
+type outboxRepo struct{ db database.DB }
+
+func NewOutboxRepository(db database.DB) OutboxRepository {
+	return &outboxRepo{db: db}
+}
+
+func (r *outboxRepo) Add(
+	ctx context.Context,
+	tx database.Tx,
+	messages ...types.OutboxMessage,
+) error {
+	for _, m := range messages {
+		err := tx.ExecContext(
+			ctx,
+			`INSERT INTO event_outbox (name, payload) VALUES ($1, $2::jsonb)`,
+			m.Name,
+			string(m.Payload),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *outboxRepo) Claim(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]types.OutboxMessage, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH claimed AS (
+			UPDATE event_outbox o
+			SET locked_until = NOW() + MAKE_INTERVAL(secs => $2),
+				attempts = o.attempts + 1
+			FROM (
+				SELECT id
+				FROM event_outbox
+				WHERE dispatched_at IS NULL
+					AND (locked_until IS NULL OR locked_until < NOW())
+				ORDER BY id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			) pending
+			WHERE o.id = pending.id
+			RETURNING o.id, o.name, o.payload, o.attempts, o.created_at
+		)
+		SELECT id, name, payload, attempts, created_at
+		FROM claimed
+		ORDER BY id
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []types.OutboxMessage{}
+	for rows.Next() {
+		var m types.OutboxMessage
+		err := rows.Scan(&m.ID, &m.Name, &m.Payload, &m.Attempts, &m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+func (r *outboxRepo) MarkDispatched(ctx context.Context, ids []string) error {
+	return r.db.ExecContext(
+		ctx,
+		`UPDATE event_outbox
+		SET dispatched_at = NOW(), locked_until = NULL
+		WHERE id = ANY($1)`,
+		ids,
+	)
+}
+
+func (r *outboxRepo) MarkFailed(ctx context.Context, id, reason string) error {
+	return r.db.ExecContext(
+		ctx,
+		`UPDATE event_outbox
+		SET dispatched_at = NOW(), locked_until = NULL, last_error = $2
+		WHERE id = $1`,
+		id,
+		reason,
+	)
+}
+
+func (r *outboxRepo) Prune(ctx context.Context, before time.Time) error {
+	return r.db.ExecContext(
+		ctx,
+		`DELETE FROM event_outbox
+		WHERE dispatched_at < $1 AND last_error IS NULL`,
+		before,
+	)
+}
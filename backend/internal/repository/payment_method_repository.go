@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+type PaymentMethodRepository interface {
+	List(ctx context.Context, onlyEnabled bool) ([]types.PaymentMethod, error)
+	GetByCode(ctx context.Context, code string) (types.PaymentMethod, error)
+	// Create stores the method and sets its id, hooks run in the same
+	// transaction, as they do on Update and Delete
+	Create(
+		ctx context.Context,
+		method *types.PaymentMethod,
+		hooks ...TxHook,
+	) error
+	Update(
+		ctx context.Context,
+		method types.PaymentMethod,
+		hooks ...TxHook,
+	) error
+	Delete(ctx context.Context, code string, hooks ...TxHook) error
+}
+
+type paymentMethodRepo struct{ db database.DB }
+
+func NewPaymentMethodRepository(db database.DB) PaymentMethodRepository {
+	return &paymentMethodRepo{db: db}
+}
+
+const paymentMethodColumns = `id, code, display_name, currency,
+	account_details, enabled, reference_pattern`
+
+func (r *paymentMethodRepo) List(
+	ctx context.Context,
+	onlyEnabled bool,
+) ([]types.PaymentMethod, error) {
+	query := `SELECT ` + paymentMethodColumns + ` FROM payment_methods `
+	if onlyEnabled {
+		query += `WHERE enabled `
+	}
+	query += `ORDER BY created_at`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	methods := []types.PaymentMethod{}
+	for rows.Next() {
+		method, err := scanPaymentMethod(rows)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return methods, nil
+}
+
+func (r *paymentMethodRepo) GetByCode(
+	ctx context.Context,
+	code string,
+) (types.PaymentMethod, error) {
+	row := r.db.QueryRow(
+		ctx,
+		`SELECT `+paymentMethodColumns+`
+		FROM payment_methods
+		WHERE code = LOWER($1)`,
+		code,
+	)
+	return scanPaymentMethod(row)
+}
+
+func (r *paymentMethodRepo) Create(
+	ctx context.Context,
+	method *types.PaymentMethod,
+	hooks ...TxHook,
+) error {
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		return tx.QueryRow(
+			ctx,
+			`INSERT INTO payment_methods
+			(code, display_name, currency, account_details, enabled,
+			reference_pattern)
+			VALUES (LOWER($1), $2, $3, $4, $5, $6)
+			RETURNING id`,
+			method.Code,
+			method.DisplayName,
+			method.Currency,
+			method.AccountDetails,
+			method.Enabled,
+			method.ReferencePattern,
+		).Scan(&method.ID)
+	}, hooks)
+}
+
+func (r *paymentMethodRepo) Update(
+	ctx context.Context,
+	method types.PaymentMethod,
+	hooks ...TxHook,
+) error {
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		return tx.ExecContext(
+			ctx,
+			`UPDATE payment_methods
+			SET display_name = $2,
+				currency = $3,
+				account_details = $4,
+				enabled = $5,
+				reference_pattern = $6,
+				updated_at = NOW()
+			WHERE code = LOWER($1)`,
+			method.Code,
+			method.DisplayName,
+			method.Currency,
+			method.AccountDetails,
+			method.Enabled,
+			method.ReferencePattern,
+		)
+	}, hooks)
+}
+
+func (r *paymentMethodRepo) Delete(
+	ctx context.Context,
+	code string,
+	hooks ...TxHook,
+) error {
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		return tx.ExecContext(
+			ctx,
+			`DELETE FROM payment_methods WHERE code = LOWER($1)`,
+			code,
+		)
+	}, hooks)
+}
+
+func scanPaymentMethod(row database.Row) (types.PaymentMethod, error) {
+	var method types.PaymentMethod
+	err := row.Scan(
+		&method.ID,
+		&method.Code,
+		&method.DisplayName,
+		&method.Currency,
+		&method.AccountDetails,
+		&method.Enabled,
+		&method.ReferencePattern,
+	)
+	return method, err
+}
@@ -2,16 +2,51 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"rifa/backend/internal/types"
 	database "rifa/backend/pkg/db"
 )
 
 type PriceRepository interface {
-	GetLatestPrices(ctx context.Context) (types.Prices, error)
-	Save(ctx context.Context, prices types.Prices) error
+	// GetLatestPrices returns the prices in effect for the lottery right now
+	GetLatestPrices(ctx context.Context, lotteryID string) (types.Prices, error)
+	// Save stores prices and sets their id, hooks run in the same
+	// transaction. The prices are stored unannounced.
+	Save(ctx context.Context, prices *types.Prices, hooks ...TxHook) error
+	// MarkAnnounced marks the prices called id as announced within tx
+	MarkAnnounced(ctx context.Context, tx database.Tx, id string) error
+	// AnnounceDue marks announced every price that came into effect without
+	// being announced, oldest first. hook runs in the same transaction with
+	// them, when there are any.
+	AnnounceDue(ctx context.Context, hook AnnounceHook) error
+	// GetHistory lists every price of the lottery, scheduled ones included,
+	// newest first
+	GetHistory(
+		ctx context.Context,
+		lotteryID string,
+		page,
+		perPage int,
+	) ([]types.PriceChange, int, error)
+	GetTiers(ctx context.Context, lotteryID string) ([]types.PriceTier, error)
+	// ReplaceTiers swaps the whole set of bundles of the lottery, hooks run
+	// in the same transaction
+	ReplaceTiers(
+		ctx context.Context,
+		lotteryID string,
+		tiers []types.PriceTier,
+		hooks ...TxHook,
+	) error
 }
 
+// AnnounceHook runs inside the transaction of AnnounceDue with the prices
+// that came into effect
+type AnnounceHook func(
+	ctx context.Context,
+	tx database.Tx,
+	due []types.ScheduledPrice,
+) error
+
 type priceRepo struct {
 	db database.DB
 }
@@ -20,22 +55,280 @@ func NewPriceRepository(db database.DB) PriceRepository {
 	return &priceRepo{db: db}
 }
 
-func (r *priceRepo) GetLatestPrices(ctx context.Context) (types.Prices, error) {
+func (r *priceRepo) GetLatestPrices(
+	ctx context.Context,
+	lotteryID string,
+) (types.Prices, error) {
 	const query = `
-	SELECT bs_amount, usd_amount
+	SELECT id, lottery_id, bs_amount, usd_amount, effective_from, created_by
 	FROM prices
-	ORDER BY created_at DESC
+	WHERE lottery_id = $1 AND effective_from <= NOW()
+	ORDER BY effective_from DESC, created_at DESC
 	LIMIT 1
 	`
 	var price types.Prices
-	err := r.db.QueryRow(ctx, query).Scan(&price.BsAmount, &price.UsdAmount)
+	err := r.db.QueryRow(ctx, query, lotteryID).Scan(
+		&price.ID,
+		&price.LotteryID,
+		&price.BsAmount,
+		&price.UsdAmount,
+		&price.EffectiveFrom,
+		&price.CreatedBy,
+	)
 	return price, err
 }
 
-func (r *priceRepo) Save(ctx context.Context, prices types.Prices) error {
+func (r *priceRepo) Save(
+	ctx context.Context,
+	prices *types.Prices,
+	hooks ...TxHook,
+) error {
 	const query = `
-	INSERT INTO prices (bs_amount, usd_amount)
-	VALUES ($1, $2)
+	INSERT INTO prices
+	(lottery_id, bs_amount, usd_amount, effective_from, created_by)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id
 	`
-	return r.db.ExecContext(ctx, query, prices.BsAmount, prices.UsdAmount)
+	if prices.EffectiveFrom.IsZero() {
+		prices.EffectiveFrom = time.Now()
+	}
+
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		return tx.QueryRow(
+			ctx,
+			query,
+			prices.LotteryID,
+			prices.BsAmount,
+			prices.UsdAmount,
+			prices.EffectiveFrom,
+			prices.CreatedBy,
+		).Scan(&prices.ID)
+	}, hooks)
+}
+
+func (r *priceRepo) MarkAnnounced(
+	ctx context.Context,
+	tx database.Tx,
+	id string,
+) error {
+	return tx.ExecContext(
+		ctx,
+		`UPDATE prices SET announced_at = NOW() WHERE id = $1`,
+		id,
+	)
+}
+
+func (r *priceRepo) AnnounceDue(ctx context.Context, hook AnnounceHook) error {
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT p.id, p.lottery_id, p.bs_amount, p.usd_amount,
+				p.effective_from, p.created_by, prev.id, prev.bs_amount,
+				prev.usd_amount, prev.effective_from, prev.created_by
+			FROM prices p
+			LEFT JOIN LATERAL (
+				SELECT q.id, q.bs_amount, q.usd_amount, q.effective_from,
+					q.created_by
+				FROM prices q
+				WHERE q.lottery_id = p.lottery_id
+					AND (q.effective_from, q.created_at) <
+						(p.effective_from, p.created_at)
+				ORDER BY q.effective_from DESC, q.created_at DESC
+				LIMIT 1
+			) prev ON TRUE
+			WHERE p.announced_at IS NULL AND p.effective_from <= NOW()
+			ORDER BY p.effective_from, p.created_at
+			FOR UPDATE OF p SKIP LOCKED
+		`)
+		if err != nil {
+			return err
+		}
+
+		due := []types.ScheduledPrice{}
+		ids := []string{}
+		for rows.Next() {
+			var (
+				price         types.ScheduledPrice
+				prevID        *string
+				prevBs        *float64
+				prevUsd       *float64
+				prevEffective *time.Time
+				prevCreatedBy *string
+			)
+			err = rows.Scan(
+				&price.Prices.ID,
+				&price.Prices.LotteryID,
+				&price.Prices.BsAmount,
+				&price.Prices.UsdAmount,
+				&price.Prices.EffectiveFrom,
+				&price.Prices.CreatedBy,
+				&prevID,
+				&prevBs,
+				&prevUsd,
+				&prevEffective,
+				&prevCreatedBy,
+			)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			if prevID != nil {
+				price.Previous = &types.Prices{
+					ID:            *prevID,
+					LotteryID:     price.Prices.LotteryID,
+					BsAmount:      *prevBs,
+					UsdAmount:     *prevUsd,
+					EffectiveFrom: *prevEffective,
+					CreatedBy:     prevCreatedBy,
+				}
+			}
+			due = append(due, price)
+			ids = append(ids, price.Prices.ID)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		if len(due) == 0 {
+			return nil
+		}
+
+		err = tx.ExecContext(
+			ctx,
+			`UPDATE prices SET announced_at = NOW() WHERE id = ANY($1)`,
+			ids,
+		)
+		if err != nil {
+			return err
+		}
+		return hook(ctx, tx, due)
+	}, nil)
+}
+
+func (r *priceRepo) GetHistory(
+	ctx context.Context,
+	lotteryID string,
+	page,
+	perPage int,
+) ([]types.PriceChange, int, error) {
+	if perPage <= 0 {
+		perPage = 10
+	}
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * perPage
+
+	rows, err := r.db.Query(ctx, `
+		SELECT p.id, p.lottery_id, p.bs_amount, p.usd_amount,
+			p.effective_from, p.created_by, p.created_at, u.name,
+			COUNT(*) OVER() AS total_count
+		FROM prices p
+		LEFT JOIN users u ON u.id = p.created_by
+		WHERE p.lottery_id = $1
+		ORDER BY p.effective_from DESC, p.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, lotteryID, perPage, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	history := []types.PriceChange{}
+	var total int
+	for rows.Next() {
+		var change types.PriceChange
+		err := rows.Scan(
+			&change.ID,
+			&change.LotteryID,
+			&change.BsAmount,
+			&change.UsdAmount,
+			&change.EffectiveFrom,
+			&change.CreatedBy,
+			&change.CreatedAt,
+			&change.CreatedByName,
+			&total,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		history = append(history, change)
+	}
+	if rows.Err() != nil {
+		return nil, 0, rows.Err()
+	}
+
+	return history, total, nil
+}
+
+func (r *priceRepo) GetTiers(
+	ctx context.Context,
+	lotteryID string,
+) ([]types.PriceTier, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, quantity, paid_quantity, bs_amount, usd_amount
+		FROM price_tiers
+		WHERE lottery_id = $1
+		ORDER BY quantity
+	`, lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tiers := []types.PriceTier{}
+	for rows.Next() {
+		var tier types.PriceTier
+		err := rows.Scan(
+			&tier.ID,
+			&tier.Quantity,
+			&tier.PaidQuantity,
+			&tier.BsAmount,
+			&tier.UsdAmount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, tier)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return tiers, nil
+}
+
+func (r *priceRepo) ReplaceTiers(
+	ctx context.Context,
+	lotteryID string,
+	tiers []types.PriceTier,
+	hooks ...TxHook,
+) error {
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		err := tx.ExecContext(
+			ctx,
+			`DELETE FROM price_tiers WHERE lottery_id = $1`,
+			lotteryID,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, tier := range tiers {
+			err = tx.ExecContext(
+				ctx,
+				`INSERT INTO price_tiers
+				(lottery_id, quantity, paid_quantity, bs_amount, usd_amount)
+				VALUES ($1, $2, $3, $4, $5)`,
+				lotteryID,
+				tier.Quantity,
+				tier.PaidQuantity,
+				tier.BsAmount,
+				tier.UsdAmount,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}, hooks)
 }
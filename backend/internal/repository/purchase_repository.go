@@ -5,26 +5,100 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"math"
+	"strconv"
+	"time"
 
 	"rifa/backend/api/httpx/dto"
 	"rifa/backend/api/httpx/form"
 	"rifa/backend/internal/types"
 	database "rifa/backend/pkg/db"
 	"rifa/backend/pkg/utils"
+
+	"github.com/google/uuid"
 )
 
+// similarScreenshotDistance is the largest hamming distance between two
+// screenshot hashes for them to be considered the same picture
+const similarScreenshotDistance = 6
+
+// minScreenshotHashBits mirrors utils.InformativeHash for the stored hashes
+const minScreenshotHashBits = 8
+
 type PurchaseRepository interface {
-	Create(ctx context.Context, p *types.Purchase) (string, error)
+	// Create stores p, filling in its id. check, when not nil, runs in the
+	// same transaction right before the insert and hooks right after it.
+	Create(
+		ctx context.Context,
+		p *types.Purchase,
+		check TxHook,
+		hooks ...TxHook,
+	) error
+	GetByID(ctx context.Context, purchaseID string) (types.Purchase, error)
+	// FindDuplicate returns the id of the oldest purchase paid like p. It
+	// holds a lock on the payment reference until tx ends, so concurrent
+	// purchases reusing it are checked one after the other.
+	FindDuplicate(
+		ctx context.Context,
+		tx database.Tx,
+		p *types.Purchase,
+	) (string, error)
+	// CountUserTickets sums the tickets, bonus ones included, of the pending
+	// and verified purchases of the user in the lottery. It holds a lock on
+	// the user's purchases in the lottery until tx ends, so concurrent
+	// purchases of the same user are counted one after the other.
+	CountUserTickets(
+		ctx context.Context,
+		tx database.Tx,
+		userID,
+		lotteryID string,
+	) (int, error)
+	// GetAll lists a page of the purchases matching filters along with their
+	// count and the cursor of the next page, nil on the last one. Given a
+	// cursor, only the purchases after it are listed, failing with
+	// types.ErrInvalidCursor when it wasn't made for the same sorting.
 	GetAll(
 		ctx context.Context,
 		filters dto.GetAllPurchases,
-	) ([]form.Purchases, int, error)
-	UpdateStatus(ctx context.Context, purchaseID, status string) error
+		after *types.Cursor,
+	) ([]form.Purchases, int, *types.Cursor, error)
+	// Export calls fn with every purchase matching filters, oldest first,
+	// reading them one row at a time
+	Export(
+		ctx context.Context,
+		filters dto.PurchaseFilters,
+		fn func(types.PurchaseExport) error,
+	) error
+	UpdateStatuses(
+		ctx context.Context,
+		purchaseIDs []string,
+		status types.PurchaseStatus,
+		hook TransitionHook,
+	) ([]types.StatusChange, error)
+	CancelPending(
+		ctx context.Context,
+		purchaseIDs []string,
+		hook TransitionHook,
+	) ([]types.StatusChange, error)
+	// ListExpiring returns the pending purchases whose verification deadline
+	// is less than warnBefore away and whose buyer wasn't warned yet. The
+	// warning never comes before half the time to verify has passed, so
+	// short deadlines don't warn buyers right after they purchase.
+	ListExpiring(
+		ctx context.Context,
+		warnBefore time.Duration,
+	) ([]types.ExpiringPurchase, error)
+	MarkExpiryWarned(ctx context.Context, purchaseIDs []string) error
+	// ListExpired returns the ids of the pending purchases past their
+	// verification deadline
+	ListExpired(ctx context.Context) ([]string, error)
+	// GetLeaderboard ranks the buyers of lotteryID by verified tickets,
+	// counting pending ones apart, along with the number of buyers
 	GetLeaderboard(
 		ctx context.Context,
+		lotteryID string,
 		filters dto.GetMostPurchases,
-	) ([]form.MostPurchases, error)
+	) ([]form.MostPurchases, int, error)
 	FindUserPurchasesByTicket(
 		ctx context.Context,
 		lotteryID,
@@ -41,36 +115,277 @@ func NewPurchaseRepository(db database.DB) PurchaseRepository {
 func (r *purchaseRepo) Create(
 	ctx context.Context,
 	p *types.Purchase,
+	check TxHook,
+	hooks ...TxHook,
+) error {
+	const query = `
+	INSERT INTO purchases
+	(user_id, quantity, monto_bs, monto_usd, payment_method,
+	transaction_digits, payment_screenshot, status, created_at,
+	duplicate_of, screenshot_hash, price_id, amount_mismatch, bonus_tickets,
+	lottery_id)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15)
+	RETURNING id
+	`
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		if check != nil {
+			if err := check(ctx, tx); err != nil {
+				return err
+			}
+		}
+		err := tx.QueryRow(
+			ctx,
+			query,
+			p.UserID,
+			p.Quantity,
+			p.MontoBs,
+			p.MontoUSD,
+			p.PaymentMethod,
+			p.TransactionDigits,
+			p.PaymentScreenshot,
+			p.Status,
+			p.CreatedAt,
+			p.DuplicateOf,
+			int64(p.ScreenshotHash),
+			p.PriceID,
+			p.AmountMismatch,
+			p.BonusTickets,
+			p.LotteryID,
+		).Scan(&p.ID)
+		if err != nil {
+			return err
+		}
+		return matchScreenshot(ctx, tx, p)
+	}, hooks)
+}
+
+// matchScreenshot stores which purchases have a screenshot like the one of
+// p, both ways, so listings don't compare every pair of hashes
+func matchScreenshot(ctx context.Context, tx database.Tx, p *types.Purchase) error {
+	if !utils.InformativeHash(p.ScreenshotHash) {
+		return nil
+	}
+
+	// Purchases created at the same time would miss each other's screenshot
+	err := tx.ExecContext(
+		ctx,
+		`SELECT pg_advisory_xact_lock(hashtextextended('purchases:screenshots', 0))`,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.ExecContext(
+		ctx,
+		`WITH similar AS (
+			SELECT s.id
+			FROM purchases s
+			WHERE s.id <> $1
+				AND s.screenshot_hash IS NOT NULL
+				AND hamming_distance(s.screenshot_hash, 0) BETWEEN $3 AND 64 - $3
+				AND hamming_distance(s.screenshot_hash, $2) <= $4
+		)
+		INSERT INTO purchase_screenshot_matches (purchase_id, similar_id)
+		SELECT $1::uuid, id FROM similar
+		UNION ALL
+		SELECT id, $1::uuid FROM similar
+		ON CONFLICT DO NOTHING`,
+		p.ID,
+		int64(p.ScreenshotHash),
+		minScreenshotHashBits,
+		similarScreenshotDistance,
+	)
+}
+
+func (r *purchaseRepo) CountUserTickets(
+	ctx context.Context,
+	tx database.Tx,
+	userID,
+	lotteryID string,
+) (int, error) {
+	err := tx.ExecContext(
+		ctx,
+		`SELECT pg_advisory_xact_lock(
+			hashtextextended('purchases:' || $1 || ':' || $2, 0)
+		)`,
+		userID,
+		lotteryID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	// Pending purchases left without tickets by older failed assignments
+	// don't hold anything and aren't counted
+	var count int
+	err = tx.QueryRow(
+		ctx,
+		`SELECT COALESCE(SUM(p.quantity + p.bonus_tickets), 0)
+		FROM purchases p
+		WHERE p.user_id = $1
+			AND p.lottery_id = $2
+			AND (p.status = 'verified' OR (
+				p.status = 'pending'
+				AND EXISTS (SELECT 1 FROM tickets t WHERE t.purchase_id = p.id)
+			))`,
+		userID,
+		lotteryID,
+	).Scan(&count)
+	return count, err
+}
+
+// FindDuplicate returns the id of the oldest non cancelled purchase paid with
+// the same method, transaction digits and amounts as p, or an empty string
+// when there is none. Pending purchases left without tickets by older failed
+// assignments never went through and don't count.
+func (r *purchaseRepo) FindDuplicate(
+	ctx context.Context,
+	tx database.Tx,
+	p *types.Purchase,
 ) (string, error) {
+	err := tx.ExecContext(
+		ctx,
+		`SELECT pg_advisory_xact_lock(
+			hashtextextended('payment:' || LOWER($1) || ':' || $2, 0)
+		)`,
+		p.PaymentMethod,
+		p.TransactionDigits,
+	)
+	if err != nil {
+		return "", err
+	}
+
 	var id string
-	err := r.db.QueryRow(
+	err = tx.QueryRow(
 		ctx,
-		`INSERT INTO purchases
-		(user_id, quantity, monto_bs, monto_usd, payment_method, 
-		transaction_digits, payment_screenshot, status, created_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
-		RETURNING id`,
-		p.UserID,
-		p.Quantity,
-		p.MontoBs,
-		p.MontoUSD,
+		`SELECT p.id
+		FROM purchases p
+		WHERE LOWER(p.payment_method) = LOWER($1)
+			AND p.transaction_digits = $2
+			AND p.monto_bs IS NOT DISTINCT FROM $3::numeric(12,2)
+			AND p.monto_usd IS NOT DISTINCT FROM $4::numeric(12,2)
+			AND p.status != 'cancelled'
+			AND (p.status != 'pending'
+				OR EXISTS (SELECT 1 FROM tickets t WHERE t.purchase_id = p.id))
+		ORDER BY p.created_at ASC
+		LIMIT 1`,
 		p.PaymentMethod,
 		p.TransactionDigits,
-		p.PaymentScreenshot,
-		p.Status,
-		p.CreatedAt,
+		p.MontoBs,
+		p.MontoUSD,
 	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
 	return id, err
 }
 
+// GetByID returns a purchase without its payment screenshot
+func (r *purchaseRepo) GetByID(
+	ctx context.Context,
+	purchaseID string,
+) (types.Purchase, error) {
+	var p types.Purchase
+	err := r.db.QueryRow(
+		ctx,
+		`SELECT id, user_id, quantity, COALESCE(monto_bs, 0),
+		COALESCE(monto_usd, 0), payment_method, transaction_digits, status,
+		created_at
+		FROM purchases
+		WHERE id = $1`,
+		purchaseID,
+	).Scan(
+		&p.ID,
+		&p.UserID,
+		&p.Quantity,
+		&p.MontoBs,
+		&p.MontoUSD,
+		&p.PaymentMethod,
+		&p.TransactionDigits,
+		&p.Status,
+		&p.CreatedAt,
+	)
+	return p, err
+}
+
+// purchaseSort is a column purchases can be sorted by: its SQL expression,
+// the type cursor values are cast to, how to read it from a purchase and how
+// to check a cursor value can be cast
+type purchaseSort struct {
+	expr  string
+	cast  string
+	value func(form.Purchases) string
+	valid func(string) bool
+}
+
+var purchaseSorts = map[string]purchaseSort{
+	"date": {"p.created_at", "timestamp", func(p form.Purchases) string {
+		return p.CreatedAt.Format(time.RFC3339Nano)
+	}, validTime},
+	"amountBs": {"COALESCE(p.monto_bs, 0)", "numeric", func(p form.Purchases) string {
+		return strconv.FormatFloat(p.MontoBs, 'f', -1, 64)
+	}, validAmount},
+	"amountUsd": {"COALESCE(p.monto_usd, 0)", "numeric", func(p form.Purchases) string {
+		return strconv.FormatFloat(p.MontoUSD, 'f', -1, 64)
+	}, validAmount},
+	"quantity": {"p.quantity", "int", func(p form.Purchases) string {
+		return strconv.Itoa(p.Quantity)
+	}, func(v string) bool {
+		_, err := strconv.ParseInt(v, 10, 32)
+		return err == nil
+	}},
+}
+
+func validTime(v string) bool {
+	_, err := time.Parse(time.RFC3339Nano, v)
+	return err == nil
+}
+
+func validAmount(v string) bool {
+	amount, err := strconv.ParseFloat(v, 64)
+	return err == nil && !math.IsNaN(amount) && !math.IsInf(amount, 0)
+}
+
 func (r *purchaseRepo) GetAll(
 	ctx context.Context,
 	filters dto.GetAllPurchases,
-) ([]form.Purchases, int, error) {
+	after *types.Cursor,
+) ([]form.Purchases, int, *types.Cursor, error) {
+	sortKey := filters.Sort
+	sort, ok := purchaseSorts[sortKey]
+	if !ok {
+		sortKey = "date"
+		sort = purchaseSorts[sortKey]
+	}
+	order, direction, comparison := "desc", "DESC", "<"
+	if filters.Order == "asc" {
+		order, direction, comparison = "asc", "ASC", ">"
+	}
+	// Cursor values are cast in the query, so they're checked beforehand
+	if after != nil && (after.Sort != sortKey ||
+		after.Order != order ||
+		!sort.valid(after.Value) ||
+		uuid.Validate(after.ID) != nil) {
+		return nil, 0, nil, types.ErrInvalidCursor
+	}
+
 	var args []interface{}
 	query := `SELECT u.id, u.name, u.email, u.phone,
     p.id, p.quantity, p.monto_bs, p.monto_usd, p.payment_method,
     p.transaction_digits, p.payment_screenshot, p.status, p.created_at,
+	p.duplicate_of, p.amount_mismatch, p.bonus_tickets,
+	(
+		SELECT c.code
+		FROM coupon_redemptions r JOIN coupons c ON c.id = r.coupon_id
+		WHERE r.purchase_id = p.id
+	) AS coupon,
+	ARRAY(
+		SELECT s.id::text
+		FROM purchase_screenshot_matches m
+		JOIN purchases s ON s.id = m.similar_id
+		WHERE m.purchase_id = p.id
+		ORDER BY s.created_at
+	) AS similar_screenshots,
 	COALESCE(
   	ARRAY_AGG(t.number ORDER BY t.number) FILTER (WHERE t.number IS NOT NULL),
   	'{}') AS numbers,
@@ -78,12 +393,40 @@ func (r *purchaseRepo) GetAll(
 	FROM purchases p JOIN users u ON p.user_id = u.id
 	LEFT JOIN tickets t ON t.purchase_id = p.id `
 
-	argIdx := 1
-	if filters.PurchaseStatus != "" {
-		query += "WHERE p.status = $" + fmt.Sprint(argIdx) + " "
-		args = append(args, filters.PurchaseStatus)
-		argIdx++
+	conditions, args := purchaseConditions(filters.PurchaseFilters, args)
+	total := -1
+	if after != nil {
+		// The window count would only see the purchases after the cursor,
+		// the total is counted apart over every page
+		countConditions, countArgs := purchaseConditions(
+			filters.PurchaseFilters,
+			nil,
+		)
+		err := r.db.QueryRow(
+			ctx,
+			`SELECT COUNT(*)
+			FROM purchases p JOIN users u ON p.user_id = u.id `+
+				whereClause(countConditions),
+			countArgs...,
+		).Scan(&total)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+
+		// Ties on the sorted column are broken by id, so every purchase
+		// lands on exactly one page
+		args = append(args, after.Value, after.ID)
+		conditions = append(conditions, fmt.Sprintf(
+			"(%s, p.id) %s ($%d::%s, $%d::uuid)",
+			sort.expr,
+			comparison,
+			len(args)-1,
+			sort.cast,
+			len(args),
+		))
 	}
+	query += whereClause(conditions)
+	argIdx := len(args) + 1
 
 	// Pagination logic
 	perPage := filters.ItemCount
@@ -91,28 +434,29 @@ func (r *purchaseRepo) GetAll(
 		perPage = 10
 	}
 	page := filters.Page
-	if page <= 0 {
+	if page <= 0 || after != nil {
 		page = 1
 	}
 	offset := (page - 1) * perPage
-	query += `
+	query += fmt.Sprintf(`
 	GROUP BY 
 		u.id, u.name, u.email, u.phone,
 		p.id, p.quantity, p.monto_bs, p.monto_usd, p.payment_method,
-		p.transaction_digits, p.payment_screenshot, p.status, p.created_at
-	ORDER BY p.created_at DESC
-	`
+		p.transaction_digits, p.payment_screenshot, p.status, p.created_at,
+		p.duplicate_of, p.amount_mismatch, p.bonus_tickets, p.screenshot_hash
+	ORDER BY %s %s, p.id %s
+	`, sort.expr, direction, direction)
+	// One more purchase than the page holds tells whether there's a next one
 	query += fmt.Sprintf("LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
-	args = append(args, perPage, offset)
+	args = append(args, perPage+1, offset)
 
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 	defer rows.Close()
 
 	var purchases []form.Purchases
-	var total int
 	for rows.Next() {
 		var p form.Purchases
 		var numbers []int
@@ -131,72 +475,509 @@ func (r *purchaseRepo) GetAll(
 			&p.PaymentScreenshot,
 			&p.Status,
 			&p.CreatedAt,
+			&p.DuplicateOf,
+			&p.AmountMismatch,
+			&p.BonusTickets,
+			&p.Coupon,
+			&p.SimilarScreenshots,
 			&numbers,
 			&rowTotal,
 		)
 		if err != nil {
-			return nil, 0, err
+			return nil, 0, nil, err
 		}
-		if total == 0 {
+		if total < 0 {
 			total = rowTotal
 		}
 		p.Tickets = utils.ConvertToStrSlice(numbers)
 		purchases = append(purchases, p)
 	}
-	return purchases, total, nil
+	if rows.Err() != nil {
+		return nil, 0, nil, rows.Err()
+	}
+
+	total = max(total, 0)
+
+	var next *types.Cursor
+	if len(purchases) > perPage {
+		purchases = purchases[:perPage]
+		last := purchases[len(purchases)-1]
+		next = &types.Cursor{
+			Sort:  sortKey,
+			Order: order,
+			Value: sort.value(last),
+			ID:    last.ID,
+		}
+	}
+	return purchases, total, next, nil
 }
 
-func (r *purchaseRepo) UpdateStatus(
+func (r *purchaseRepo) Export(
 	ctx context.Context,
-	purchaseID,
-	status string,
+	filters dto.PurchaseFilters,
+	fn func(types.PurchaseExport) error,
 ) error {
-	tx, err := r.db.BeginTx(ctx)
+	conditions, args := purchaseConditions(filters, nil)
+	query := `
+		SELECT p.id, p.user_id, COALESCE(p.lottery_id::text, ''),
+			COALESCE(l.name, ''), u.name, u.email, u.phone, p.quantity,
+			p.bonus_tickets, COALESCE(p.monto_bs, 0), COALESCE(p.monto_usd, 0),
+			p.payment_method, p.transaction_digits, p.status, p.created_at,
+			COALESCE((
+				SELECT c.code
+				FROM coupon_redemptions r JOIN coupons c ON c.id = r.coupon_id
+				WHERE r.purchase_id = p.id
+			), ''),
+			ARRAY(
+				SELECT t.number FROM tickets t
+				WHERE t.purchase_id = p.id
+				ORDER BY t.number
+			)
+		FROM purchases p
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN lotteries l ON l.id = p.lottery_id
+		` + whereClause(conditions) + `
+		ORDER BY p.created_at, p.id
+	`
+
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return err
 	}
-	defer func() {
+	defer rows.Close()
+
+	for rows.Next() {
+		var p types.PurchaseExport
+		err := rows.Scan(
+			&p.ID,
+			&p.UserID,
+			&p.LotteryID,
+			&p.LotteryName,
+			&p.BuyerName,
+			&p.BuyerEmail,
+			&p.BuyerPhone,
+			&p.Quantity,
+			&p.BonusTickets,
+			&p.MontoBs,
+			&p.MontoUSD,
+			&p.PaymentMethod,
+			&p.TransactionDigits,
+			&p.Status,
+			&p.CreatedAt,
+			&p.Coupon,
+			&p.Tickets,
+		)
 		if err != nil {
-			if rbErr := tx.Rollback(ctx); rbErr != nil {
-				log.Printf("transaction rollback failed: %v", rbErr)
-			}
-		} else {
-			if cmErr := tx.Commit(ctx); cmErr != nil {
-				log.Printf("transaction commit failed: %v", cmErr)
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// purchaseConditions turns filters into SQL conditions on purchases p,
+// appending their values to args
+func purchaseConditions(
+	filters dto.PurchaseFilters,
+	args []any,
+) ([]string, []any) {
+	var conditions []string
+	addCondition := func(cond string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filters.PurchaseStatus != "" {
+		addCondition("p.status = $%d", filters.PurchaseStatus)
+	}
+	if filters.Flagged {
+		conditions = append(
+			conditions,
+			"(p.duplicate_of IS NOT NULL OR p.amount_mismatch)",
+		)
+	}
+	if !filters.From.IsZero() {
+		addCondition("p.created_at >= $%d", filters.From)
+	}
+	if !filters.To.IsZero() {
+		addCondition("p.created_at < $%d", filters.To)
+	}
+	if filters.PaymentMethod != "" {
+		addCondition("p.payment_method = $%d", filters.PaymentMethod)
+	}
+	if filters.LotteryID != "" {
+		addCondition("p.lottery_id::text = $%d", filters.LotteryID)
+	}
+	if filters.Email != "" {
+		addCondition("u.email ILIKE $%d", likePattern(filters.Email))
+	}
+	if filters.Phone != "" {
+		addCondition("u.phone LIKE $%d", likePattern(filters.Phone))
+	}
+	if filters.Reference != "" {
+		addCondition("p.transaction_digits = $%d", filters.Reference)
+	}
+	if filters.Ticket != "" {
+		addCondition(`EXISTS (
+			SELECT 1 FROM tickets ft
+			WHERE ft.purchase_id = p.id AND ft.number = $%d::int
+		)`, filters.Ticket)
+	}
+	amount := "COALESCE(p.monto_usd, 0)"
+	if filters.Currency == string(types.CurrencyBs) {
+		amount = "COALESCE(p.monto_bs, 0)"
+	}
+	if filters.MinAmount > 0 {
+		addCondition(amount+" >= $%d", filters.MinAmount)
+	}
+	if filters.MaxAmount > 0 {
+		addCondition(amount+" <= $%d", filters.MaxAmount)
+	}
+
+	return conditions, args
+}
+
+// TransitionHook runs inside the transaction of a status transition with the
+// changes applied, when there are any
+type TransitionHook func(
+	ctx context.Context,
+	tx database.Tx,
+	applied []types.StatusChange,
+) error
+
+// UpdateStatuses moves every purchase in purchaseIDs to status within a single
+// transaction. Purchases that don't exist or can't legally move to status are
+// left untouched and reported in the returned results, in input order.
+// purchaseIDs must be distinct, well formed UUIDs. hook may be nil.
+func (r *purchaseRepo) UpdateStatuses(
+	ctx context.Context,
+	purchaseIDs []string,
+	status types.PurchaseStatus,
+	hook TransitionHook,
+) ([]types.StatusChange, error) {
+	return r.transition(
+		ctx,
+		purchaseIDs,
+		status,
+		func(current types.PurchaseStatus) bool {
+			return current.CanTransitionTo(status)
+		},
+		hook,
+	)
+}
+
+// CancelPending cancels the purchases in purchaseIDs that are still pending,
+// leaving the ones verified in the meantime untouched
+func (r *purchaseRepo) CancelPending(
+	ctx context.Context,
+	purchaseIDs []string,
+	hook TransitionHook,
+) ([]types.StatusChange, error) {
+	return r.transition(
+		ctx,
+		purchaseIDs,
+		types.StatusCancelled,
+		func(current types.PurchaseStatus) bool {
+			return current == types.StatusPending
+		},
+		hook,
+	)
+}
+
+// transition moves the purchases whose current status is allowed to status,
+// as described in UpdateStatuses
+func (r *purchaseRepo) transition(
+	ctx context.Context,
+	purchaseIDs []string,
+	status types.PurchaseStatus,
+	allowed func(current types.PurchaseStatus) bool,
+	hook TransitionHook,
+) ([]types.StatusChange, error) {
+	var changes []types.StatusChange
+	err := inTx(ctx, r.db, func(tx database.Tx) error {
+		var err error
+		changes, err = transitionTx(ctx, tx, purchaseIDs, status, allowed, hook)
+		return err
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// transitionTx applies the transition of transition within tx
+func transitionTx(
+	ctx context.Context,
+	tx database.Tx,
+	purchaseIDs []string,
+	status types.PurchaseStatus,
+	allowed func(current types.PurchaseStatus) bool,
+	hook TransitionHook,
+) ([]types.StatusChange, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT p.id, p.user_id, p.lottery_id, p.quantity,
+			COALESCE(p.monto_bs, 0), COALESCE(p.monto_usd, 0), p.payment_method,
+			p.transaction_digits, p.status, p.created_at, u.email
+		FROM purchases p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.id = ANY($1)
+		FOR UPDATE OF p
+	`, purchaseIDs)
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]types.StatusChange{}
+	for rows.Next() {
+		var (
+			p     types.Purchase
+			email string
+		)
+		err = rows.Scan(
+			&p.ID,
+			&p.UserID,
+			&p.LotteryID,
+			&p.Quantity,
+			&p.MontoBs,
+			&p.MontoUSD,
+			&p.PaymentMethod,
+			&p.TransactionDigits,
+			&p.Status,
+			&p.CreatedAt,
+			&email,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		found[p.ID] = types.StatusChange{
+			PurchaseID: p.ID,
+			Purchase:   &p,
+			BuyerEmail: email,
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	changes := make([]types.StatusChange, 0, len(purchaseIDs))
+	applied := []string{}
+	for _, id := range purchaseIDs {
+		change, ok := found[id]
+		switch {
+		case !ok:
+			change = types.StatusChange{
+				PurchaseID: id,
+				Result:     types.StatusChangeNotFound,
 			}
+		case !allowed(change.Purchase.Status):
+			change.Result = types.StatusChangeIllegal
+		default:
+			change.Result = types.StatusChangeApplied
+			applied = append(applied, id)
 		}
-	}()
+		changes = append(changes, change)
+	}
+
+	if len(applied) == 0 {
+		return changes, nil
+	}
 
 	err = tx.ExecContext(ctx,
-		`UPDATE purchases SET status = $1 WHERE id = $2`,
-		status, purchaseID,
+		`UPDATE purchases SET status = $1 WHERE id = ANY($2)`,
+		status, applied,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if status == string(types.StatusCancelled) {
-		err = tx.ExecContext(ctx, `
-			UPDATE tickets
-			SET
-				status = 'available',
-				user_id = NULL,
-				purchase_id = NULL,
-				reserved_at = NULL
-			WHERE purchase_id = $1 AND user_id IS NOT NULL
-		`, purchaseID)
+	if status.ReleasesTickets() {
+		err = releaseTickets(ctx, tx, applied)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		err = releaseCoupons(ctx, tx, applied)
+	} else {
+		err = markTickets(ctx, tx, applied, status.TicketStatus())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if hook != nil {
+		done := []types.StatusChange{}
+		for _, change := range changes {
+			if change.Result == types.StatusChangeApplied {
+				done = append(done, change)
+			}
+		}
+		err = hook(ctx, tx, done)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	return nil
+	return changes, nil
+}
+
+func (r *purchaseRepo) ListExpiring(
+	ctx context.Context,
+	warnBefore time.Duration,
+) ([]types.ExpiringPurchase, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, user_id, quantity, monto_bs, monto_usd, payment_method,
+			transaction_digits, status, created_at, email, deadline
+		FROM (
+			SELECT p.id, p.user_id, p.quantity, COALESCE(p.monto_bs, 0) AS monto_bs,
+				COALESCE(p.monto_usd, 0) AS monto_usd, p.payment_method,
+				p.transaction_digits, p.status, p.created_at, u.email,
+				p.created_at + MAKE_INTERVAL(hours => l.pending_expiry_hours)
+					AS deadline,
+				MAKE_INTERVAL(hours => l.pending_expiry_hours) AS expiry_window
+			FROM purchases p
+			JOIN users u ON u.id = p.user_id
+			JOIN lotteries l ON l.id = p.lottery_id
+			WHERE p.status = 'pending'
+				AND p.expiry_warned_at IS NULL
+				AND l.pending_expiry_hours IS NOT NULL
+		) pending
+		WHERE deadline > NOW()
+			AND deadline - LEAST(MAKE_INTERVAL(secs => $1), expiry_window / 2)
+				<= NOW()
+		ORDER BY deadline
+	`, warnBefore.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expiring := []types.ExpiringPurchase{}
+	for rows.Next() {
+		var e types.ExpiringPurchase
+		err := rows.Scan(
+			&e.ID,
+			&e.UserID,
+			&e.Quantity,
+			&e.MontoBs,
+			&e.MontoUSD,
+			&e.PaymentMethod,
+			&e.TransactionDigits,
+			&e.Status,
+			&e.CreatedAt,
+			&e.BuyerEmail,
+			&e.Deadline,
+		)
+		if err != nil {
+			return nil, err
+		}
+		expiring = append(expiring, e)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return expiring, nil
+}
+
+func (r *purchaseRepo) MarkExpiryWarned(
+	ctx context.Context,
+	purchaseIDs []string,
+) error {
+	return r.db.ExecContext(
+		ctx,
+		`UPDATE purchases SET expiry_warned_at = NOW() WHERE id = ANY($1)`,
+		purchaseIDs,
+	)
+}
+
+func (r *purchaseRepo) ListExpired(ctx context.Context) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT p.id
+		FROM purchases p
+		JOIN lotteries l ON l.id = p.lottery_id
+		WHERE p.status = 'pending'
+			AND l.pending_expiry_hours IS NOT NULL
+			AND p.created_at + MAKE_INTERVAL(hours => l.pending_expiry_hours)
+				<= NOW()
+		ORDER BY p.created_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return ids, nil
+}
+
+// releaseTickets makes the tickets of the given purchases available again
+func releaseTickets(
+	ctx context.Context,
+	tx database.Tx,
+	purchaseIDs []string,
+) error {
+	return tx.ExecContext(ctx, `
+		UPDATE tickets
+		SET
+			status = 'available',
+			user_id = NULL,
+			purchase_id = NULL,
+			reserved_at = NULL
+		WHERE purchase_id = ANY($1) AND user_id IS NOT NULL
+	`, purchaseIDs)
+}
+
+// markTickets moves the tickets still held by the given purchases to status,
+// so verifying a purchase sells its reserved tickets and moving it back to
+// pending reserves them again
+func markTickets(
+	ctx context.Context,
+	tx database.Tx,
+	purchaseIDs []string,
+	status types.TicketStatus,
+) error {
+	return tx.ExecContext(ctx, `
+		UPDATE tickets
+		SET status = $1
+		WHERE purchase_id = ANY($2)
+			AND status IN ('reserved', 'sold')
+			AND status != $1
+	`, status, purchaseIDs)
+}
+
+// releaseCoupons stops the coupon redemptions of the given purchases from
+// counting towards the coupon limits
+func releaseCoupons(
+	ctx context.Context,
+	tx database.Tx,
+	purchaseIDs []string,
+) error {
+	return tx.ExecContext(ctx, `
+		UPDATE coupon_redemptions
+		SET released_at = NOW()
+		WHERE purchase_id = ANY($1) AND released_at IS NULL
+	`, purchaseIDs)
 }
 
 func (r *purchaseRepo) GetLeaderboard(
 	ctx context.Context,
+	lotteryID string,
 	filters dto.GetMostPurchases,
-) ([]form.MostPurchases, error) {
+) ([]form.MostPurchases, int, error) {
 	perPage := filters.ItemCount
 	if perPage <= 0 {
 		perPage = 10
@@ -207,28 +988,50 @@ func (r *purchaseRepo) GetLeaderboard(
 	}
 	offset := (page - 1) * perPage
 
-	query := `
-		SELECT id, name, email, phone, quantity
-		FROM (
-			SELECT u.id, u.name, u.email, u.phone,
-				SUM(p.quantity) as quantity
-			FROM purchases p
-			JOIN users u ON p.user_id = u.id
-			WHERE p.status = 'verified'
-			GROUP BY u.id, u.name, u.email, u.phone
-		) AS leaderboard
-		ORDER BY quantity DESC
-		LIMIT $1 OFFSET $2
-	`
-	args := []interface{}{perPage, offset}
+	args := []any{lotteryID}
+	conditions := []string{
+		"p.lottery_id = $1",
+		"p.status IN ('verified', 'pending')",
+	}
+	if !filters.From.IsZero() {
+		args = append(args, filters.From)
+		conditions = append(
+			conditions,
+			fmt.Sprintf("p.created_at >= $%d", len(args)),
+		)
+	}
+	if !filters.To.IsZero() {
+		args = append(args, filters.To)
+		conditions = append(
+			conditions,
+			fmt.Sprintf("p.created_at < $%d", len(args)),
+		)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT u.id, u.name, u.email, u.phone,
+			COALESCE(SUM(p.quantity) FILTER (WHERE p.status = 'verified'), 0)
+				AS quantity,
+			COALESCE(SUM(p.quantity) FILTER (WHERE p.status = 'pending'), 0)
+				AS pending,
+			COUNT(*) OVER() AS total_count
+		FROM purchases p
+		JOIN users u ON p.user_id = u.id
+		%s
+		GROUP BY u.id, u.name, u.email, u.phone
+		ORDER BY quantity DESC, pending DESC, u.id
+		LIMIT $%d OFFSET $%d
+	`, whereClause(conditions), len(args)+1, len(args)+2)
+	args = append(args, perPage, offset)
 
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var leaderboard []form.MostPurchases
+	leaderboard := []form.MostPurchases{}
+	var total int
 	for rows.Next() {
 		var entry form.MostPurchases
 		err := rows.Scan(
@@ -237,14 +1040,19 @@ func (r *purchaseRepo) GetLeaderboard(
 			&entry.User.Email,
 			&entry.User.Phone,
 			&entry.Quantity,
+			&entry.Pending,
+			&total,
 		)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		leaderboard = append(leaderboard, entry)
 	}
+	if rows.Err() != nil {
+		return nil, 0, rows.Err()
+	}
 
-	return leaderboard, nil
+	return leaderboard, total, nil
 }
 
 func (r *purchaseRepo) FindUserPurchasesByTicket(
@@ -263,7 +1071,8 @@ func (r *purchaseRepo) FindUserPurchasesByTicket(
 			) AS ticket_numbers
 		FROM tickets t
 		JOIN users u ON u.id = t.user_id
-		WHERE t.lottery_id = $1 AND t.number = $2 AND t.status = 'sold'
+		WHERE t.lottery_id = $1 AND t.number = $2
+			AND t.status IN ('reserved', 'sold')
 		LIMIT 1
 	`
 	var (
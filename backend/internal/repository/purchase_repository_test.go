@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/internal/types"
+)
+
+func TestGetAll_InvalidCursor(t *testing.T) {
+	repo := NewPurchaseRepository(&fakeDB{})
+	valid := types.Cursor{
+		Sort:  "date",
+		Order: "desc",
+		Value: "2025-03-04T15:30:00Z",
+		ID:    "0196a0c4-8d1e-7b3a-9f00-1c2d3e4f5a6b",
+	}
+
+	tests := map[string]struct {
+		sort   string
+		tamper func(c *types.Cursor)
+	}{
+		"other sort":  {"quantity", func(c *types.Cursor) {}},
+		"other order": {"date", func(c *types.Cursor) { c.Order = "asc" }},
+		"bad date":    {"date", func(c *types.Cursor) { c.Value = "yesterday" }},
+		"bad id":      {"date", func(c *types.Cursor) { c.ID = "1 OR 1=1" }},
+		"infinite amount": {"amountBs", func(c *types.Cursor) {
+			c.Sort, c.Value = "amountBs", "Inf"
+		}},
+		"quantity overflow": {"quantity", func(c *types.Cursor) {
+			c.Sort, c.Value = "quantity", "4294967296"
+		}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cursor := valid
+			tt.tamper(&cursor)
+
+			_, _, _, err := repo.GetAll(
+				context.Background(),
+				dto.GetAllPurchases{Sort: tt.sort, Order: "desc"},
+				&cursor,
+			)
+			if !errors.Is(err, types.ErrInvalidCursor) {
+				t.Fatalf("GetAll() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
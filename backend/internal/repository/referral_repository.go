@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/api/httpx/form"
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+	"rifa/backend/pkg/utils"
+)
+
+type ReferralRepository interface {
+	GetUserIDByCode(ctx context.Context, code string) (string, error)
+	// GetReferrerID returns the id of the user who referred userID, or an
+	// empty string when they registered without a code
+	GetReferrerID(ctx context.Context, userID string) (string, error)
+	// CreateReward stores the reward unless the referred user already earned
+	// one, reporting whether it was created. hooks run in the same
+	// transaction only when it was.
+	CreateReward(
+		ctx context.Context,
+		reward *types.ReferralReward,
+		hooks ...TxHook,
+	) (bool, error)
+	SetRewardTickets(
+		ctx context.Context,
+		tx database.Tx,
+		rewardID string,
+		numbers []int,
+	) error
+	GetSummary(ctx context.Context, userID string) (form.ReferralSummary, error)
+	GetTopReferrers(
+		ctx context.Context,
+		filters dto.GetTopReferrers,
+	) ([]form.TopReferrer, int, error)
+}
+
+// errRewardExists rolls back the transaction of a reward already earned
+var errRewardExists = errors.New("referral reward already exists")
+
+type referralRepo struct{ db database.DB }
+
+func NewReferralRepository(db database.DB) ReferralRepository {
+	return &referralRepo{db: db}
+}
+
+func (r *referralRepo) GetUserIDByCode(
+	ctx context.Context,
+	code string,
+) (string, error) {
+	var id string
+	err := r.db.QueryRow(
+		ctx,
+		`SELECT id FROM users WHERE referral_code = UPPER($1)`,
+		code,
+	).Scan(&id)
+	return id, err
+}
+
+func (r *referralRepo) GetReferrerID(
+	ctx context.Context,
+	userID string,
+) (string, error) {
+	var referrerID *string
+	err := r.db.QueryRow(
+		ctx,
+		`SELECT referred_by FROM users WHERE id = $1`,
+		userID,
+	).Scan(&referrerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil || referrerID == nil {
+		return "", err
+	}
+	return *referrerID, nil
+}
+
+func (r *referralRepo) CreateReward(
+	ctx context.Context,
+	reward *types.ReferralReward,
+	hooks ...TxHook,
+) (bool, error) {
+	err := inTx(ctx, r.db, func(tx database.Tx) error {
+		err := tx.QueryRow(
+			ctx,
+			`INSERT INTO referral_rewards
+			(referrer_id, referred_id, purchase_id, lottery_id)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (referred_id) DO NOTHING
+			RETURNING id`,
+			reward.ReferrerID,
+			reward.ReferredID,
+			reward.PurchaseID,
+			reward.LotteryID,
+		).Scan(&reward.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errRewardExists
+		}
+		return err
+	}, hooks)
+	if errors.Is(err, errRewardExists) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *referralRepo) SetRewardTickets(
+	ctx context.Context,
+	tx database.Tx,
+	rewardID string,
+	numbers []int,
+) error {
+	return tx.ExecContext(
+		ctx,
+		`UPDATE referral_rewards SET ticket_numbers = $2 WHERE id = $1`,
+		rewardID,
+		numbers,
+	)
+}
+
+func (r *referralRepo) GetSummary(
+	ctx context.Context,
+	userID string,
+) (form.ReferralSummary, error) {
+	var (
+		summary form.ReferralSummary
+		numbers []int
+	)
+	err := r.db.QueryRow(
+		ctx,
+		`SELECT u.referral_code,
+			(SELECT COUNT(*) FROM users r WHERE r.referred_by = u.id),
+			(SELECT COUNT(*) FROM referral_rewards rr WHERE rr.referrer_id = u.id),
+			COALESCE((
+				SELECT ARRAY_AGG(n ORDER BY n)
+				FROM referral_rewards rr, UNNEST(rr.ticket_numbers) AS n
+				WHERE rr.referrer_id = u.id
+			), '{}')
+		FROM users u
+		WHERE u.id = $1`,
+		userID,
+	).Scan(&summary.Code, &summary.Referred, &summary.Converted, &numbers)
+	if err != nil {
+		return form.ReferralSummary{}, err
+	}
+
+	summary.RewardTickets = utils.ConvertToStrSlice(numbers)
+	return summary, nil
+}
+
+func (r *referralRepo) GetTopReferrers(
+	ctx context.Context,
+	filters dto.GetTopReferrers,
+) ([]form.TopReferrer, int, error) {
+	perPage := filters.ItemCount
+	if perPage <= 0 {
+		perPage = 10
+	}
+	page := filters.Page
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * perPage
+
+	rows, err := r.db.Query(ctx, `
+		SELECT u.id, u.name, u.email, u.phone,
+			COUNT(*) AS referred,
+			COUNT(rr.id) AS converted,
+			COALESCE(SUM(CARDINALITY(rr.ticket_numbers)), 0) AS reward_tickets,
+			COUNT(*) OVER() AS total_count
+		FROM users referred
+		JOIN users u ON u.id = referred.referred_by
+		LEFT JOIN referral_rewards rr ON rr.referred_id = referred.id
+		GROUP BY u.id, u.name, u.email, u.phone
+		ORDER BY converted DESC, referred DESC, u.name
+		LIMIT $1 OFFSET $2
+	`, perPage, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	referrers := []form.TopReferrer{}
+	var total int
+	for rows.Next() {
+		var entry form.TopReferrer
+		err := rows.Scan(
+			&entry.User.ID,
+			&entry.User.Name,
+			&entry.User.Email,
+			&entry.User.Phone,
+			&entry.Referred,
+			&entry.Converted,
+			&entry.RewardTickets,
+			&total,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		referrers = append(referrers, entry)
+	}
+	if rows.Err() != nil {
+		return nil, 0, rows.Err()
+	}
+
+	return referrers, total, nil
+}
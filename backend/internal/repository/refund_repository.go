@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+type RefundRepository interface {
+	// Create stores the refund and sets its id. check, when not nil, runs in
+	// the same transaction right before the insert and hooks right after it,
+	// as they do on Complete.
+	Create(
+		ctx context.Context,
+		refund *types.Refund,
+		check TxHook,
+		hooks ...TxHook,
+	) error
+	GetByID(ctx context.Context, refundID string) (types.Refund, error)
+	List(
+		ctx context.Context,
+		filters dto.GetRefunds,
+	) ([]types.Refund, int, error)
+	// RefundedAmount sums the refunds of the purchase in the currency,
+	// pending ones included. It locks the purchase until tx ends, so
+	// concurrent refunds of it are summed one after the other.
+	RefundedAmount(
+		ctx context.Context,
+		tx database.Tx,
+		purchaseID string,
+		currency types.Currency,
+	) (float64, error)
+	// Complete marks a pending refund as completed, failing with
+	// sql.ErrNoRows when it isn't pending anymore
+	Complete(ctx context.Context, refund types.Refund, hooks ...TxHook) error
+}
+
+type refundRepo struct{ db database.DB }
+
+func NewRefundRepository(db database.DB) RefundRepository {
+	return &refundRepo{db: db}
+}
+
+const refundColumns = `id, purchase_id, amount, currency, method, reference,
+	reason, full_refund, proof, status, created_by, completed_by, created_at,
+	completed_at`
+
+func (r *refundRepo) Create(
+	ctx context.Context,
+	refund *types.Refund,
+	check TxHook,
+	hooks ...TxHook,
+) error {
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		if check != nil {
+			if err := check(ctx, tx); err != nil {
+				return err
+			}
+		}
+		return tx.QueryRow(
+			ctx,
+			`INSERT INTO refunds
+			(purchase_id, amount, currency, method, reason, full_refund,
+			created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id`,
+			refund.PurchaseID,
+			refund.Amount,
+			refund.Currency,
+			refund.Method,
+			refund.Reason,
+			refund.Full,
+			refund.CreatedBy,
+		).Scan(&refund.ID)
+	}, hooks)
+}
+
+func (r *refundRepo) GetByID(
+	ctx context.Context,
+	refundID string,
+) (types.Refund, error) {
+	row := r.db.QueryRow(
+		ctx,
+		`SELECT `+refundColumns+` FROM refunds WHERE id = $1`,
+		refundID,
+	)
+	return scanRefund(row)
+}
+
+func (r *refundRepo) List(
+	ctx context.Context,
+	filters dto.GetRefunds,
+) ([]types.Refund, int, error) {
+	var (
+		args       []any
+		conditions []string
+	)
+	if filters.Status != "" {
+		args = append(args, filters.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filters.PurchaseID != "" {
+		args = append(args, filters.PurchaseID)
+		conditions = append(
+			conditions,
+			fmt.Sprintf("purchase_id::text = $%d", len(args)),
+		)
+	}
+
+	perPage := filters.ItemCount
+	if perPage <= 0 {
+		perPage = 10
+	}
+	page := filters.Page
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * perPage
+
+	query := `SELECT ` + refundColumns + `, COUNT(*) OVER() AS total_count
+	FROM refunds `
+	query += whereClause(conditions)
+	query += fmt.Sprintf(
+		"ORDER BY created_at DESC LIMIT $%d OFFSET $%d",
+		len(args)+1,
+		len(args)+2,
+	)
+	args = append(args, perPage, offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	refunds := []types.Refund{}
+	var total int
+	for rows.Next() {
+		refund, err := scanRefund(rows, &total)
+		if err != nil {
+			return nil, 0, err
+		}
+		refunds = append(refunds, refund)
+	}
+	if rows.Err() != nil {
+		return nil, 0, rows.Err()
+	}
+
+	return refunds, total, nil
+}
+
+func (r *refundRepo) RefundedAmount(
+	ctx context.Context,
+	tx database.Tx,
+	purchaseID string,
+	currency types.Currency,
+) (float64, error) {
+	err := tx.ExecContext(
+		ctx,
+		`SELECT 1 FROM purchases WHERE id = $1 FOR UPDATE`,
+		purchaseID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var amount float64
+	err = tx.QueryRow(
+		ctx,
+		`SELECT COALESCE(SUM(amount), 0)
+		FROM refunds
+		WHERE purchase_id = $1 AND currency = $2`,
+		purchaseID,
+		currency,
+	).Scan(&amount)
+	return amount, err
+}
+
+func (r *refundRepo) Complete(
+	ctx context.Context,
+	refund types.Refund,
+	hooks ...TxHook,
+) error {
+	// The update waits on a concurrent completion of the refund and then
+	// finds it completed, returning no row
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		var id string
+		return tx.QueryRow(
+			ctx,
+			`UPDATE refunds
+			SET status = 'completed',
+				reference = $2,
+				proof = $3,
+				completed_by = $4,
+				completed_at = NOW()
+			WHERE id = $1 AND status = 'pending'
+			RETURNING id`,
+			refund.ID,
+			refund.Reference,
+			refund.Proof,
+			refund.CompletedBy,
+		).Scan(&id)
+	}, hooks)
+}
+
+// scanRefund reads a row selected with refundColumns, followed by the extra
+// destinations given
+func scanRefund(row database.Row, extra ...any) (types.Refund, error) {
+	var refund types.Refund
+	dest := []any{
+		&refund.ID,
+		&refund.PurchaseID,
+		&refund.Amount,
+		&refund.Currency,
+		&refund.Method,
+		&refund.Reference,
+		&refund.Reason,
+		&refund.Full,
+		&refund.Proof,
+		&refund.Status,
+		&refund.CreatedBy,
+		&refund.CompletedBy,
+		&refund.CreatedAt,
+		&refund.CompletedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return refund, err
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/api/httpx/form"
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+// revenueTotals aggregates the purchases p into the columns scanned by
+// revenueDest. Purchases carry both amounts but were paid in the currency of
+// their payment method m, so each amount only sums the purchases paid in it.
+const revenueTotals = `COUNT(*), COALESCE(SUM(p.quantity), 0),
+	COALESCE(SUM(p.monto_bs) FILTER (WHERE m.currency = 'bs'), 0),
+	COALESCE(SUM(p.monto_usd) FILTER (WHERE m.currency = 'usd'), 0)`
+
+// revenueSource is what revenueTotals aggregates
+const revenueSource = `purchases p
+	LEFT JOIN payment_methods m ON m.code = LOWER(p.payment_method)`
+
+type ReportRepository interface {
+	// GetRevenue aggregates the purchases of lotteryID made within the
+	// filters date range
+	GetRevenue(
+		ctx context.Context,
+		lotteryID string,
+		filters dto.GetRevenueReport,
+	) (form.RevenueReport, error)
+	// GetSales returns the tickets claimed by the purchases of lotteryID per
+	// interval, "hour" or "day", from the first sale up to now
+	GetSales(
+		ctx context.Context,
+		lotteryID,
+		interval string,
+	) ([]types.SalesBucket, error)
+	CountTickets(ctx context.Context, lotteryID string) (types.TicketCounts, error)
+}
+
+type reportRepo struct{ db database.DB }
+
+func NewReportRepository(db database.DB) ReportRepository {
+	return &reportRepo{db: db}
+}
+
+func (r *reportRepo) GetRevenue(
+	ctx context.Context,
+	lotteryID string,
+	filters dto.GetRevenueReport,
+) (form.RevenueReport, error) {
+	report := form.RevenueReport{
+		LotteryID:       lotteryID,
+		ByPeriod:        []form.RevenuePeriod{},
+		ByPaymentMethod: []form.RevenueByMethod{},
+		ByStatus:        []form.RevenueByStatus{},
+	}
+
+	args := []any{lotteryID}
+	conditions := []string{"p.lottery_id = $1"}
+	if !filters.From.IsZero() {
+		args = append(args, filters.From)
+		conditions = append(
+			conditions,
+			fmt.Sprintf("p.created_at >= $%d", len(args)),
+		)
+	}
+	if !filters.To.IsZero() {
+		args = append(args, filters.To)
+		conditions = append(
+			conditions,
+			fmt.Sprintf("p.created_at < $%d", len(args)),
+		)
+	}
+	where := whereClause(conditions)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT p.status, `+revenueTotals+`
+		FROM `+revenueSource+`
+		`+where+`
+		GROUP BY p.status
+		ORDER BY p.status
+	`, args...)
+	if err != nil {
+		return form.RevenueReport{}, err
+	}
+	for rows.Next() {
+		var entry form.RevenueByStatus
+		err = rows.Scan(revenueDest(&entry.Status, &entry.RevenueTotals)...)
+		if err != nil {
+			rows.Close()
+			return form.RevenueReport{}, err
+		}
+		switch entry.Status {
+		case "verified":
+			report.Verified = entry.RevenueTotals
+		case "pending":
+			report.Pending = entry.RevenueTotals
+		}
+		report.ByStatus = append(report.ByStatus, entry)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return form.RevenueReport{}, err
+	}
+
+	verified := whereClause(append(conditions, "p.status = 'verified'"))
+
+	// groupBy is validated by the handler and bound as a parameter, so it
+	// never reaches the query text
+	periodArgs := append(append([]any{}, args...), filters.GroupBy)
+	rows, err = r.db.Query(ctx, fmt.Sprintf(`
+		SELECT DATE_TRUNC($%d, p.created_at) AS period, %s
+		FROM %s
+		%s
+		GROUP BY period
+		ORDER BY period
+	`, len(periodArgs), revenueTotals, revenueSource, verified), periodArgs...)
+	if err != nil {
+		return form.RevenueReport{}, err
+	}
+	for rows.Next() {
+		var entry form.RevenuePeriod
+		err = rows.Scan(revenueDest(&entry.Period, &entry.RevenueTotals)...)
+		if err != nil {
+			rows.Close()
+			return form.RevenueReport{}, err
+		}
+		report.ByPeriod = append(report.ByPeriod, entry)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return form.RevenueReport{}, err
+	}
+
+	rows, err = r.db.Query(ctx, `
+		SELECT p.payment_method, `+revenueTotals+`
+		FROM `+revenueSource+`
+		`+verified+`
+		GROUP BY p.payment_method
+		ORDER BY p.payment_method
+	`, args...)
+	if err != nil {
+		return form.RevenueReport{}, err
+	}
+	for rows.Next() {
+		var entry form.RevenueByMethod
+		err = rows.Scan(
+			revenueDest(&entry.PaymentMethod, &entry.RevenueTotals)...,
+		)
+		if err != nil {
+			rows.Close()
+			return form.RevenueReport{}, err
+		}
+		report.ByPaymentMethod = append(report.ByPaymentMethod, entry)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return form.RevenueReport{}, err
+	}
+
+	// Refunds count within the range of the purchases they give money back for
+	err = r.db.QueryRow(ctx, `
+		SELECT COUNT(*),
+			COALESCE(SUM(f.amount) FILTER (WHERE f.currency = 'bs'), 0),
+			COALESCE(SUM(f.amount) FILTER (WHERE f.currency = 'usd'), 0)
+		FROM refunds f
+		JOIN purchases p ON p.id = f.purchase_id
+		`+whereClause(append(conditions, "f.status = 'completed'")),
+		args...,
+	).Scan(
+		&report.Refunded.Refunds,
+		&report.Refunded.AmountBs,
+		&report.Refunded.AmountUSD,
+	)
+	if err != nil {
+		return form.RevenueReport{}, err
+	}
+
+	return report, nil
+}
+
+func (r *reportRepo) GetSales(
+	ctx context.Context,
+	lotteryID,
+	interval string,
+) ([]types.SalesBucket, error) {
+	// Released tickets lose their purchase, so only tickets still held by a
+	// purchase are counted. Empty intervals are filled in with zeros.
+	rows, err := r.db.Query(ctx, `
+		WITH sales AS (
+			SELECT DATE_TRUNC($2, p.created_at) AS period,
+				COUNT(*) FILTER (WHERE t.status = 'sold') AS sold,
+				COUNT(*) FILTER (WHERE t.status = 'reserved') AS reserved
+			FROM tickets t
+			JOIN purchases p ON p.id = t.purchase_id
+			WHERE t.lottery_id = $1
+			GROUP BY 1
+		)
+		SELECT s.period, COALESCE(sales.sold, 0), COALESCE(sales.reserved, 0)
+		FROM GENERATE_SERIES(
+			(SELECT MIN(period) FROM sales),
+			DATE_TRUNC($2, LOCALTIMESTAMP),
+			('1 ' || $2)::interval
+		) AS s(period)
+		LEFT JOIN sales ON sales.period = s.period
+		ORDER BY s.period
+	`, lotteryID, interval)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []types.SalesBucket{}
+	for rows.Next() {
+		var b types.SalesBucket
+		if err := rows.Scan(&b.Period, &b.Sold, &b.Reserved); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return buckets, nil
+}
+
+func (r *reportRepo) CountTickets(
+	ctx context.Context,
+	lotteryID string,
+) (types.TicketCounts, error) {
+	var counts types.TicketCounts
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE status = 'available'),
+			COUNT(*) FILTER (WHERE status = 'reserved'),
+			COUNT(*) FILTER (WHERE status = 'sold')
+		FROM tickets
+		WHERE lottery_id = $1
+	`, lotteryID).Scan(&counts.Available, &counts.Reserved, &counts.Sold)
+	return counts, err
+}
+
+// revenueDest returns the scan destinations of a row holding a group key
+// followed by the revenueTotals columns
+func revenueDest(key any, t *form.RevenueTotals) []any {
+	return []any{key, &t.Purchases, &t.Tickets, &t.MontoBs, &t.MontoUSD}
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"rifa/backend/api/httpx/form"
+	database "rifa/backend/pkg/db"
+)
+
+type SearchRepository interface {
+	// Search ranks users by name, email and phone, purchases by transaction
+	// digits and id, and the tickets of the active lottery by number.
+	// idPrefix and ticket are only matched when not nil.
+	Search(
+		ctx context.Context,
+		term string,
+		idPrefix *string,
+		ticket *int,
+		limit int,
+	) ([]form.SearchHit, error)
+}
+
+type searchRepo struct{ db database.DB }
+
+func NewSearchRepository(db database.DB) SearchRepository {
+	return &searchRepo{db: db}
+}
+
+func (r *searchRepo) Search(
+	ctx context.Context,
+	term string,
+	idPrefix *string,
+	ticket *int,
+	limit int,
+) ([]form.SearchHit, error) {
+	// Exact matches rank 1, partial ones below, fuzzy ones by similarity
+	const query = `
+		WITH q AS (
+			SELECT $1::text AS term, PLAINTO_TSQUERY('simple', $1) AS ts
+		)
+		SELECT 'user', u.id::text, u.id, u.name, u.email, u.phone,
+			NULL::text, NULL::text, NULL::text, NULL::int, NULL::timestamp,
+			NULL::int,
+			CASE WHEN LOWER(u.email) = LOWER(q.term) OR u.phone = q.term THEN 1
+			ELSE GREATEST(
+				SIMILARITY(u.name, q.term),
+				SIMILARITY(u.email, q.term),
+				SIMILARITY(u.phone, q.term),
+				TS_RANK(TO_TSVECTOR('simple', u.name), q.ts)
+			) END::float8
+		FROM users u, q
+		WHERE u.name % q.term
+			OR u.email % q.term
+			OR u.email ILIKE $2
+			OR u.phone LIKE $2
+			OR TO_TSVECTOR('simple', u.name) @@ q.ts
+		UNION ALL
+		SELECT 'purchase', p.id::text, u.id, u.name, u.email, u.phone,
+			p.id::text, p.status, p.transaction_digits, p.quantity, p.created_at,
+			NULL::int,
+			CASE WHEN p.transaction_digits = q.term OR p.id::text = LOWER(q.term)
+				THEN 1 ELSE 0.6 END::float8
+		FROM purchases p JOIN users u ON u.id = p.user_id, q
+		WHERE p.transaction_digits LIKE $2 OR p.id::text LIKE $3
+		UNION ALL
+		SELECT 'ticket', t.id::text, u.id, u.name, u.email, u.phone,
+			t.purchase_id::text, NULL::text, NULL::text, NULL::int,
+			NULL::timestamp, t.number, 1::float8
+		FROM tickets t
+		JOIN lotteries l ON l.id = t.lottery_id AND l.active
+		JOIN users u ON u.id = t.user_id
+		WHERE t.number = $4 AND t.status != 'available'
+		ORDER BY 13 DESC, 1, 2
+		LIMIT $5
+	`
+
+	rows, err := r.db.Query(
+		ctx,
+		query,
+		term,
+		likePattern(term),
+		idPrefix,
+		ticket,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []form.SearchHit{}
+	for rows.Next() {
+		var (
+			hit        form.SearchHit
+			purchaseID *string
+			status     *string
+			digits     *string
+			quantity   *int
+			createdAt  *time.Time
+			number     *int
+		)
+		err := rows.Scan(
+			&hit.Type,
+			&hit.ID,
+			&hit.User.ID,
+			&hit.User.Name,
+			&hit.User.Email,
+			&hit.User.Phone,
+			&purchaseID,
+			&status,
+			&digits,
+			&quantity,
+			&createdAt,
+			&number,
+			&hit.Rank,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if purchaseID != nil {
+			hit.Purchase = &form.SearchPurchase{ID: *purchaseID}
+			if status != nil {
+				hit.Purchase.Status = *status
+				hit.Purchase.TransactionDigits = *digits
+				hit.Purchase.Quantity = *quantity
+				hit.Purchase.CreatedAt = *createdAt
+			}
+		}
+		if number != nil {
+			hit.Ticket = strconv.Itoa(*number)
+		}
+		hits = append(hits, hit)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return hits, nil
+}
@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 
 	"rifa/backend/internal/types"
 	db "rifa/backend/pkg/db"
@@ -20,6 +19,18 @@ type TicketRepository interface {
 		purchaseID string,
 		selectedNumbers []string,
 		quantity int,
+		hooks ...TxHook,
+	) ([]types.Ticket, error)
+	// AssignTicketsTx assigns tickets like AssignTickets within tx, for
+	// callers that assign them along with other changes
+	AssignTicketsTx(
+		ctx context.Context,
+		tx db.Tx,
+		lotteryID,
+		userID,
+		purchaseID string,
+		selectedNumbers []string,
+		quantity int,
 	) ([]types.Ticket, error)
 	GetActiveLotteryID(ctx context.Context) (string, error)
 	GetUnavailableNumbers(
@@ -30,7 +41,7 @@ type TicketRepository interface {
 	GetAvailabilityPercentage(
 		ctx context.Context,
 		lotteryID string,
-	) (float64, error)
+	) (types.TicketAvailability, error)
 	GetUserTickets(
 		ctx context.Context,
 		userID string,
@@ -47,7 +58,10 @@ func NewTicketRepository(db db.DB) TicketRepository {
 }
 
 // AssignTickets Assign the selected numbers (if provided and available), and
-// assign randoms for the rest
+// assign randoms for the rest. Tickets of a purchase are reserved until its
+// payment is verified. An empty purchaseID assigns sold tickets that don't
+// belong to any purchase, like referral rewards. The hooks run in the same
+// transaction once the tickets are assigned.
 func (r *ticketRepo) AssignTickets(
 	ctx context.Context,
 	lotteryID,
@@ -55,25 +69,47 @@ func (r *ticketRepo) AssignTickets(
 	purchaseID string,
 	selectedNumbers []string,
 	quantity int,
+	hooks ...TxHook,
 ) ([]types.Ticket, error) {
-	tx, err := r.db.BeginTx(ctx)
+	var assigned []types.Ticket
+	err := inTx(ctx, r.db, func(tx db.Tx) error {
+		var err error
+		assigned, err = r.AssignTicketsTx(
+			ctx,
+			tx,
+			lotteryID,
+			userID,
+			purchaseID,
+			selectedNumbers,
+			quantity,
+		)
+		return err
+	}, hooks)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if err != nil {
-			if rbErr := tx.Rollback(ctx); rbErr != nil {
-				log.Printf("transaction rollback failed: %v", rbErr)
-			}
-		} else {
-			if cmErr := tx.Commit(ctx); cmErr != nil {
-				log.Printf("transaction commit failed: %v", cmErr)
-			}
-		}
-	}()
+	return assigned, nil
+}
+
+func (r *ticketRepo) AssignTicketsTx(
+	ctx context.Context,
+	tx db.Tx,
+	lotteryID,
+	userID,
+	purchaseID string,
+	selectedNumbers []string,
+	quantity int,
+) ([]types.Ticket, error) {
+	var purchaseRef *string
+	status := types.TicketSold
+	if purchaseID != "" {
+		purchaseRef = &purchaseID
+		status = types.TicketReserved
+	}
 
 	var intNumbers []int
 	if len(selectedNumbers) > 0 {
+		var err error
 		intNumbers, err = utils.ConvertToIntSlice(selectedNumbers)
 		if err != nil {
 			return nil, err
@@ -87,10 +123,11 @@ func (r *ticketRepo) AssignTickets(
 		ticket := types.Ticket{}
 		res := tx.QueryRow(ctx,
 			`UPDATE tickets
-			 SET user_id = $1, status = 'sold', purchase_id = $2
-			 WHERE lottery_id = $3 AND number = $4 AND status = 'available'
+			 SET user_id = $1, status = $2,
+				 purchase_id = NULLIF($3, '')::uuid, reserved_at = NOW()
+			 WHERE lottery_id = $4 AND number = $5 AND status = 'available'
 			 RETURNING id, number`,
-			userID, purchaseID, lotteryID, number,
+			userID, status, purchaseID, lotteryID, number,
 		)
 		if err := res.Scan(&ticket.ID, &ticket.Number); err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
@@ -102,8 +139,8 @@ func (r *ticketRepo) AssignTickets(
 			return nil, err
 		}
 		ticket.UserID = &userID
-		ticket.Status = "sold"
-		ticket.PurchaseID = &purchaseID
+		ticket.Status = status
+		ticket.PurchaseID = purchaseRef
 		ticket.LotteryID = lotteryID
 		assigned = append(assigned, ticket)
 	}
@@ -140,10 +177,11 @@ func (r *ticketRepo) AssignTickets(
 		// Assign and return full updated rows
 		rows, err = tx.Query(ctx,
 			`UPDATE tickets
-			 SET user_id = $1, status = 'sold', purchase_id = $2
-			 WHERE id = ANY($3)
+			 SET user_id = $1, status = $2,
+				 purchase_id = NULLIF($3, '')::uuid, reserved_at = NOW()
+			 WHERE id = ANY($4)
 			 RETURNING id, number`,
-			userID, purchaseID, ticketIDs,
+			userID, status, purchaseID, ticketIDs,
 		)
 		if err != nil {
 			return nil, err
@@ -156,8 +194,8 @@ func (r *ticketRepo) AssignTickets(
 				return nil, err
 			}
 			ticket.UserID = &userID
-			ticket.Status = "sold"
-			ticket.PurchaseID = &purchaseID
+			ticket.Status = status
+			ticket.PurchaseID = purchaseRef
 			ticket.LotteryID = lotteryID
 			assigned = append(assigned, ticket)
 		}
@@ -220,25 +258,29 @@ func (r *ticketRepo) GetUnavailableNumbers(
 func (r *ticketRepo) GetAvailabilityPercentage(
 	ctx context.Context,
 	lotteryID string,
-) (float64, error) {
+) (types.TicketAvailability, error) {
 	query := `
 		SELECT
-			COUNT(*) FILTER (WHERE status != 'available')::float / 10000 * 100 AS percent
+			COUNT(*) FILTER (WHERE status = 'sold')::float / 10000 * 100,
+			COUNT(*) FILTER (WHERE status = 'reserved')::float / 10000 * 100
 		FROM tickets
 		WHERE lottery_id = $1
 	`
 
-	var percent float64
-	err := r.db.QueryRow(ctx, query, lotteryID).Scan(&percent)
+	var availability types.TicketAvailability
+	err := r.db.QueryRow(ctx, query, lotteryID).Scan(
+		&availability.Sold,
+		&availability.Reserved,
+	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			// No rows, return 0% safely
-			return 0, nil
+			return types.TicketAvailability{}, nil
 		}
-		return 0, err
+		return types.TicketAvailability{}, err
 	}
 
-	return percent, nil
+	return availability, nil
 }
 
 func (r *ticketRepo) GetUserTickets(
@@ -21,8 +21,9 @@ func NewUserRepository(db database.DB) UserRepository {
 
 func (r *userRepo) CreateUser(ctx context.Context, user *types.User) error {
 	query := `
-		INSERT INTO users (name, email, phone, password, role)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users
+		(name, email, phone, password, role, referral_code, referred_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING null
 	`
 	return r.db.ExecContext(
@@ -33,6 +34,8 @@ func (r *userRepo) CreateUser(ctx context.Context, user *types.User) error {
 		user.Phone,
 		user.Password,
 		user.Role,
+		user.ReferralCode,
+		user.ReferredBy,
 	)
 }
 
@@ -0,0 +1,391 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rifa/backend/api/httpx/dto"
+	"rifa/backend/internal/types"
+	database "rifa/backend/pkg/db"
+)
+
+type WebhookRepository interface {
+	ListEndpoints(ctx context.Context) ([]types.WebhookEndpoint, error)
+	GetEndpoint(ctx context.Context, id string) (types.WebhookEndpoint, error)
+	// CreateEndpoint stores the endpoint and sets its id and date, hooks run
+	// in the same transaction, as they do on update and delete
+	CreateEndpoint(
+		ctx context.Context,
+		endpoint *types.WebhookEndpoint,
+		hooks ...TxHook,
+	) error
+	UpdateEndpoint(
+		ctx context.Context,
+		endpoint types.WebhookEndpoint,
+		hooks ...TxHook,
+	) error
+	DeleteEndpoint(ctx context.Context, id string, hooks ...TxHook) error
+	// ListSubscribed returns the active endpoints subscribed to the events
+	// called name
+	ListSubscribed(
+		ctx context.Context,
+		name string,
+	) ([]types.WebhookEndpoint, error)
+	// CreateDeliveries stores a pending delivery of the event for each
+	// endpoint it wasn't stored for yet. The new deliveries are returned held
+	// for lease, so they aren't retried while first attempted.
+	CreateDeliveries(
+		ctx context.Context,
+		eventID,
+		name string,
+		payload []byte,
+		endpointIDs []string,
+		lease time.Duration,
+	) ([]types.WebhookDelivery, error)
+	// ClaimDue holds for lease up to limit pending deliveries whose next
+	// attempt is due, oldest first
+	ClaimDue(
+		ctx context.Context,
+		limit int,
+		lease time.Duration,
+	) ([]types.WebhookDelivery, error)
+	// SaveAttempt stores the outcome of the latest attempt of delivery
+	SaveAttempt(ctx context.Context, delivery types.WebhookDelivery) error
+	ListDeliveries(
+		ctx context.Context,
+		filters dto.GetWebhookDeliveries,
+	) ([]types.WebhookDelivery, int, error)
+}
+
+type webhookRepo struct{ db database.DB }
+
+func NewWebhookRepository(db database.DB) WebhookRepository {
+	return &webhookRepo{db: db}
+}
+
+const webhookEndpointColumns = `id, url, secret, events, description, active,
+	created_at`
+
+const webhookDeliveryColumns = `id, endpoint_id, event_id, event, payload,
+	status, attempts, response_status, response_body, error, next_attempt_at,
+	last_attempt_at, created_at`
+
+func (r *webhookRepo) ListEndpoints(
+	ctx context.Context,
+) ([]types.WebhookEndpoint, error) {
+	return r.queryEndpoints(
+		ctx,
+		`SELECT `+webhookEndpointColumns+`
+		FROM webhook_endpoints
+		ORDER BY created_at`,
+	)
+}
+
+func (r *webhookRepo) GetEndpoint(
+	ctx context.Context,
+	id string,
+) (types.WebhookEndpoint, error) {
+	row := r.db.QueryRow(
+		ctx,
+		`SELECT `+webhookEndpointColumns+`
+		FROM webhook_endpoints
+		WHERE id = $1`,
+		id,
+	)
+	return scanWebhookEndpoint(row)
+}
+
+func (r *webhookRepo) CreateEndpoint(
+	ctx context.Context,
+	endpoint *types.WebhookEndpoint,
+	hooks ...TxHook,
+) error {
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		return tx.QueryRow(
+			ctx,
+			`INSERT INTO webhook_endpoints
+			(url, secret, events, description, active)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at`,
+			endpoint.URL,
+			endpoint.Secret,
+			endpoint.Events,
+			endpoint.Description,
+			endpoint.Active,
+		).Scan(&endpoint.ID, &endpoint.CreatedAt)
+	}, hooks)
+}
+
+func (r *webhookRepo) UpdateEndpoint(
+	ctx context.Context,
+	endpoint types.WebhookEndpoint,
+	hooks ...TxHook,
+) error {
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		return tx.ExecContext(
+			ctx,
+			`UPDATE webhook_endpoints
+			SET url = $2,
+				secret = $3,
+				events = $4,
+				description = $5,
+				active = $6,
+				updated_at = NOW()
+			WHERE id = $1`,
+			endpoint.ID,
+			endpoint.URL,
+			endpoint.Secret,
+			endpoint.Events,
+			endpoint.Description,
+			endpoint.Active,
+		)
+	}, hooks)
+}
+
+func (r *webhookRepo) DeleteEndpoint(
+	ctx context.Context,
+	id string,
+	hooks ...TxHook,
+) error {
+	return inTx(ctx, r.db, func(tx database.Tx) error {
+		return tx.ExecContext(
+			ctx,
+			`DELETE FROM webhook_endpoints WHERE id = $1`,
+			id,
+		)
+	}, hooks)
+}
+
+func (r *webhookRepo) ListSubscribed(
+	ctx context.Context,
+	name string,
+) ([]types.WebhookEndpoint, error) {
+	return r.queryEndpoints(
+		ctx,
+		`SELECT `+webhookEndpointColumns+`
+		FROM webhook_endpoints
+		WHERE active AND $1 = ANY(events)
+		ORDER BY created_at`,
+		name,
+	)
+}
+
+func (r *webhookRepo) queryEndpoints(
+	ctx context.Context,
+	query string,
+	args ...any,
+) ([]types.WebhookEndpoint, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []types.WebhookEndpoint{}
+	for rows.Next() {
+		endpoint, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+func (r *webhookRepo) CreateDeliveries(
+	ctx context.Context,
+	eventID,
+	name string,
+	payload []byte,
+	endpointIDs []string,
+	lease time.Duration,
+) ([]types.WebhookDelivery, error) {
+	return r.queryDeliveries(
+		ctx,
+		`INSERT INTO webhook_deliveries
+		(endpoint_id, event_id, event, payload, next_attempt_at)
+		SELECT endpoint_id, $2, $3, $4::jsonb,
+			NOW() + MAKE_INTERVAL(secs => $5)
+		FROM UNNEST($1::uuid[]) AS endpoint_id
+		ON CONFLICT (endpoint_id, event_id) DO NOTHING
+		RETURNING `+webhookDeliveryColumns,
+		endpointIDs,
+		eventID,
+		name,
+		string(payload),
+		lease.Seconds(),
+	)
+}
+
+func (r *webhookRepo) ClaimDue(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]types.WebhookDelivery, error) {
+	return r.queryDeliveries(
+		ctx,
+		`WITH claimed AS (
+			UPDATE webhook_deliveries d
+			SET next_attempt_at = NOW() + MAKE_INTERVAL(secs => $2)
+			FROM (
+				SELECT id
+				FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			) due
+			WHERE d.id = due.id
+			RETURNING d.*
+		)
+		SELECT `+webhookDeliveryColumns+`
+		FROM claimed
+		ORDER BY created_at`,
+		limit,
+		lease.Seconds(),
+	)
+}
+
+func (r *webhookRepo) SaveAttempt(
+	ctx context.Context,
+	delivery types.WebhookDelivery,
+) error {
+	return r.db.ExecContext(
+		ctx,
+		`UPDATE webhook_deliveries
+		SET status = $2,
+			attempts = $3,
+			response_status = $4,
+			response_body = $5,
+			error = $6,
+			next_attempt_at = $7,
+			last_attempt_at = $8
+		WHERE id = $1`,
+		delivery.ID,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.ResponseStatus,
+		delivery.ResponseBody,
+		delivery.Error,
+		delivery.NextAttemptAt,
+		delivery.LastAttemptAt,
+	)
+}
+
+func (r *webhookRepo) ListDeliveries(
+	ctx context.Context,
+	filters dto.GetWebhookDeliveries,
+) ([]types.WebhookDelivery, int, error) {
+	args := []any{filters.ID}
+	conditions := []string{"endpoint_id = $1"}
+	if filters.Status != "" {
+		args = append(args, filters.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filters.Event != "" {
+		args = append(args, filters.Event)
+		conditions = append(conditions, fmt.Sprintf("event = $%d", len(args)))
+	}
+
+	perPage := filters.ItemCount
+	if perPage <= 0 {
+		perPage = 10
+	}
+	page := filters.Page
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * perPage
+
+	query := `SELECT ` + webhookDeliveryColumns + `, COUNT(*) OVER() AS total_count
+	FROM webhook_deliveries `
+	query += whereClause(conditions)
+	query += fmt.Sprintf(
+		"ORDER BY created_at DESC LIMIT $%d OFFSET $%d",
+		len(args)+1,
+		len(args)+2,
+	)
+	args = append(args, perPage, offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deliveries := []types.WebhookDelivery{}
+	var total int
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows, &total)
+		if err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if rows.Err() != nil {
+		return nil, 0, rows.Err()
+	}
+
+	return deliveries, total, nil
+}
+
+func (r *webhookRepo) queryDeliveries(
+	ctx context.Context,
+	query string,
+	args ...any,
+) ([]types.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []types.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanWebhookEndpoint(row database.Row) (types.WebhookEndpoint, error) {
+	var endpoint types.WebhookEndpoint
+	err := row.Scan(
+		&endpoint.ID,
+		&endpoint.URL,
+		&endpoint.Secret,
+		&endpoint.Events,
+		&endpoint.Description,
+		&endpoint.Active,
+		&endpoint.CreatedAt,
+	)
+	return endpoint, err
+}
+
+func scanWebhookDelivery(
+	row database.Row,
+	extra ...any,
+) (types.WebhookDelivery, error) {
+	var delivery types.WebhookDelivery
+	dest := []any{
+		&delivery.ID,
+		&delivery.EndpointID,
+		&delivery.EventID,
+		&delivery.Event,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.ResponseStatus,
+		&delivery.ResponseBody,
+		&delivery.Error,
+		&delivery.NextAttemptAt,
+		&delivery.LastAttemptAt,
+		&delivery.CreatedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return delivery, err
+}
@@ -0,0 +1,53 @@
+package types
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditPriceUpdated          AuditAction = "price.updated"
+	AuditPriceTiersUpdated     AuditAction = "price.tiers_updated"
+	AuditPurchaseStatusUpdated AuditAction = "purchase.status_updated"
+	AuditPurchaseExpired       AuditAction = "purchase.expired"
+	AuditPaymentMethodCreated  AuditAction = "payment_method.created"
+	AuditPaymentMethodUpdated  AuditAction = "payment_method.updated"
+	AuditPaymentMethodDeleted  AuditAction = "payment_method.deleted"
+	AuditExchangeRateRecorded  AuditAction = "exchange_rate.recorded"
+	AuditCouponCreated         AuditAction = "coupon.created"
+	AuditCouponUpdated         AuditAction = "coupon.updated"
+	AuditLotteryLimitsUpdated  AuditAction = "lottery.limits_updated"
+	AuditRefundCreated         AuditAction = "refund.created"
+	AuditRefundCompleted       AuditAction = "refund.completed"
+	AuditWebhookCreated        AuditAction = "webhook.created"
+	AuditWebhookUpdated        AuditAction = "webhook.updated"
+	AuditWebhookDeleted        AuditAction = "webhook.deleted"
+)
+
+const (
+	AuditTargetPrice         = "price"
+	AuditTargetPurchase      = "purchase"
+	AuditTargetPaymentMethod = "payment_method"
+	AuditTargetExchangeRate  = "exchange_rate"
+	AuditTargetCoupon        = "coupon"
+	AuditTargetLottery       = "lottery"
+	AuditTargetRefund        = "refund"
+	AuditTargetWebhook       = "webhook"
+)
+
+// Actor identifies who performed a mutation and from where
+type Actor struct {
+	ID        string `json:"id,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+type AuditEntry struct {
+	ID         string
+	Actor      Actor
+	Action     AuditAction
+	TargetType string
+	TargetID   string
+	Before     any
+	After      any
+	CreatedAt  time.Time
+}
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"math"
+	"time"
+)
+
+type CouponType string
+
+const (
+	CouponPercent      CouponType = "percent"
+	CouponFixed        CouponType = "fixed"
+	CouponBonusTickets CouponType = "bonus_tickets"
+)
+
+// Coupon is a promo code. Value is a percentage for percent coupons, a USD
+// amount for fixed ones and a number of tickets for bonus_tickets ones.
+// Nil limits, dates and lottery mean no restriction.
+type Coupon struct {
+	ID           string     `json:"id,omitempty"`
+	Code         string     `json:"code"`
+	Type         CouponType `json:"type"`
+	Value        float64    `json:"value"`
+	MaxUses      *int       `json:"max_uses,omitempty"`
+	PerUserLimit *int       `json:"per_user_limit,omitempty"`
+	StartsAt     *time.Time `json:"starts_at,omitempty"`
+	EndsAt       *time.Time `json:"ends_at,omitempty"`
+	LotteryID    *string    `json:"lottery_id,omitempty"`
+	Enabled      bool       `json:"enabled"`
+	Uses         int        `json:"-"`
+}
+
+// ValidAt reports whether the coupon can be used at t for the given lottery,
+// regardless of how many times it was redeemed
+func (c Coupon) ValidAt(t time.Time, lotteryID string) bool {
+	switch {
+	case !c.Enabled:
+		return false
+	case c.StartsAt != nil && t.Before(*c.StartsAt):
+		return false
+	case c.EndsAt != nil && !t.Before(*c.EndsAt):
+		return false
+	case c.LotteryID != nil && *c.LotteryID != lotteryID:
+		return false
+	}
+	return true
+}
+
+// Discount returns the amount taken off total in the given currency. Fixed
+// discounts are converted to Bs with the same ratio as the unit prices, and
+// never exceed the total.
+func (c Coupon) Discount(total float64, currency Currency, unit Prices) float64 {
+	var discount float64
+	switch c.Type {
+	case CouponPercent:
+		discount = total * c.Value / 100
+	case CouponFixed:
+		discount = c.Value
+		if currency != CurrencyUSD && unit.UsdAmount > 0 {
+			discount = c.Value * unit.BsAmount / unit.UsdAmount
+		}
+	}
+
+	discount = math.Round(discount*100) / 100
+	return math.Min(discount, total)
+}
+
+// BonusTickets returns the tickets given on top of the ones paid for
+func (c Coupon) BonusTickets() int {
+	if c.Type != CouponBonusTickets {
+		return 0
+	}
+	return int(c.Value)
+}
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCoupon_Discount(t *testing.T) {
+	unit := Prices{BsAmount: 150, UsdAmount: 1}
+
+	tests := []struct {
+		name     string
+		coupon   Coupon
+		total    float64
+		currency Currency
+		want     float64
+	}{
+		{"percent_usd", Coupon{Type: CouponPercent, Value: 10}, 25, CurrencyUSD, 2.5},
+		{"percent_rounds_to_cents", Coupon{Type: CouponPercent, Value: 15}, 3.33, CurrencyUSD, 0.5},
+		{"fixed_usd", Coupon{Type: CouponFixed, Value: 2}, 10, CurrencyUSD, 2},
+		{"fixed_converted_to_bs", Coupon{Type: CouponFixed, Value: 2}, 1500, CurrencyBs, 300},
+		{"fixed_capped_at_total", Coupon{Type: CouponFixed, Value: 20}, 10, CurrencyUSD, 10},
+		{"bonus_tickets_no_discount", Coupon{Type: CouponBonusTickets, Value: 2}, 10, CurrencyUSD, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.coupon.Discount(tt.total, tt.currency, unit)
+			if got != tt.want {
+				t.Fatalf("Discount(%v, %s) = %v, want %v", tt.total, tt.currency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoupon_ValidAt(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	lottery := "lottery-1"
+	other := "lottery-2"
+
+	tests := []struct {
+		name   string
+		coupon Coupon
+		want   bool
+	}{
+		{"unrestricted", Coupon{Enabled: true}, true},
+		{"disabled", Coupon{}, false},
+		{"within_window", Coupon{Enabled: true, StartsAt: &past, EndsAt: &future}, true},
+		{"not_started", Coupon{Enabled: true, StartsAt: &future}, false},
+		{"ended", Coupon{Enabled: true, EndsAt: &past}, false},
+		{"same_lottery", Coupon{Enabled: true, LotteryID: &lottery}, true},
+		{"other_lottery", Coupon{Enabled: true, LotteryID: &other}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.coupon.ValidAt(now, lottery); got != tt.want {
+				t.Fatalf("ValidAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
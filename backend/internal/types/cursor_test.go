@@ -0,0 +1,31 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	want := Cursor{
+		Sort:  "date",
+		Order: "desc",
+		Value: "2025-03-04T15:30:00Z",
+		ID:    "0196a0c4-8d1e-7b3a-9f00-1c2d3e4f5a6b",
+	}
+
+	got, err := DecodeCursor(want.Encode())
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if got != want {
+		t.Fatalf("DecodeCursor() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, token := range []string{"", "not base64!", "bnVsbA", "e30"} {
+		if _, err := DecodeCursor(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", token, err)
+		}
+	}
+}
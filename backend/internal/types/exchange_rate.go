@@ -0,0 +1,12 @@
+package types
+
+import "time"
+
+// ExchangeRate is the amount of bolivares paid for one dollar
+type ExchangeRate struct {
+	ID        string    `json:"id,omitempty"`
+	Rate      float64   `json:"rate"`
+	Source    string    `json:"source"`
+	CreatedBy *string   `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package types
+
+import "time"
+
+// Lottery is a raffle with its ticket quantity limits. Nil maximums mean no
+// limit, a nil PendingExpiryHours keeps unverified purchases forever.
+type Lottery struct {
+	ID                 string    `json:"id"`
+	Name               string    `json:"name"`
+	Active             bool      `json:"active"`
+	MinPerPurchase     int       `json:"min_per_purchase"`
+	MaxPerPurchase     *int      `json:"max_per_purchase,omitempty"`
+	MaxPerUser         *int      `json:"max_per_user,omitempty"`
+	PendingExpiryHours *int      `json:"pending_expiry_hours,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package types
+
+import "time"
+
+// OutboxMessage is an encoded domain event waiting in the outbox to be
+// dispatched
+type OutboxMessage struct {
+	ID        string
+	Name      string
+	Payload   []byte
+	Attempts  int
+	CreatedAt time.Time
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"regexp"
+	"regexp/syntax"
+)
+
+// MaxReferenceLength is the longest transaction reference a purchase can
+// store
+const MaxReferenceLength = 64
+
+type PaymentMethod struct {
+	ID               string   `json:"id,omitempty"`
+	Code             string   `json:"code"`
+	DisplayName      string   `json:"display_name"`
+	Currency         Currency `json:"currency"`
+	AccountDetails   string   `json:"account_details"`
+	Enabled          bool     `json:"enabled"`
+	ReferencePattern string   `json:"reference_pattern"`
+}
+
+// ValidReference reports whether the transaction reference matches the format
+// expected for this method
+func (m PaymentMethod) ValidReference(reference string) bool {
+	if len(reference) > MaxReferenceLength {
+		return false
+	}
+	re, err := regexp.Compile(m.ReferencePattern)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(reference)
+}
+
+// ValidPattern reports whether the reference pattern compiles and is anchored
+// at both ends, e.g. ^[0-9]{6}$, so it matches whole references rather than
+// any text containing one
+func (m PaymentMethod) ValidPattern() bool {
+	re, err := syntax.Parse(m.ReferencePattern, syntax.Perl)
+	if err != nil {
+		return false
+	}
+	re = re.Simplify()
+	return re.Op == syntax.OpConcat &&
+		len(re.Sub) >= 2 &&
+		re.Sub[0].Op == syntax.OpBeginText &&
+		re.Sub[len(re.Sub)-1].Op == syntax.OpEndText
+}
@@ -0,0 +1,39 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPaymentMethod_ValidPattern(t *testing.T) {
+	tests := map[string]bool{
+		`^[0-9]{6}$`:       true,
+		`^(REF|ref)\d{8}$`: true,
+		`^[A-Z0-9]{4,12}$`: true,
+		`[0-9]{6}`:         false,
+		`^[0-9]{6}`:        false,
+		`[0-9]{6}$`:        false,
+		`^\d+$|x`:          false,
+		`^a|b$`:            false,
+		`(?m)^\d+$`:        false,
+		`^[0-9]{6\$`:       false,
+		`^(`:               false,
+	}
+	for pattern, want := range tests {
+		m := PaymentMethod{ReferencePattern: pattern}
+		if got := m.ValidPattern(); got != want {
+			t.Errorf("ValidPattern(%q) = %v, want %v", pattern, got, want)
+		}
+	}
+}
+
+func TestPaymentMethod_ValidReference(t *testing.T) {
+	m := PaymentMethod{ReferencePattern: `^[0-9]+$`}
+	if !m.ValidReference("123456") {
+		t.Error("ValidReference(123456) = false, want true")
+	}
+	long := strings.Repeat("1", MaxReferenceLength+1)
+	if m.ValidReference(long) {
+		t.Errorf("ValidReference accepted a %d digit reference", len(long))
+	}
+}
@@ -1,6 +1,102 @@
 package types
 
+import (
+	"math"
+	"time"
+)
+
+type Currency string
+
+const (
+	CurrencyBs  Currency = "bs"
+	CurrencyUSD Currency = "usd"
+)
+
 type Prices struct {
-	BsAmount  float64 `json:"bs_amount"`
-	UsdAmount float64 `json:"usd_amount"`
+	ID            string    `json:"id,omitempty"`
+	LotteryID     string    `json:"lottery_id,omitempty"`
+	BsAmount      float64   `json:"bs_amount"`
+	UsdAmount     float64   `json:"usd_amount"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	CreatedBy     *string   `json:"created_by,omitempty"`
+}
+
+// Amount returns the unit price in the given currency
+func (p Prices) Amount(currency Currency) float64 {
+	if currency == CurrencyUSD {
+		return p.UsdAmount
+	}
+	return p.BsAmount
+}
+
+// PriceChange is a row of the price history of a lottery
+type PriceChange struct {
+	Prices
+	CreatedAt     time.Time
+	CreatedByName *string
+}
+
+// ScheduledPrice is a scheduled price that came into effect, along with the
+// price it replaced, nil when the lottery had none
+type ScheduledPrice struct {
+	Prices   Prices
+	Previous *Prices
+}
+
+// PriceTier is a bundle of Quantity tickets sold either for PaidQuantity unit
+// prices or for the fixed BsAmount/UsdAmount
+type PriceTier struct {
+	ID           string   `json:"id,omitempty"`
+	Quantity     int      `json:"quantity"`
+	PaidQuantity *int     `json:"paid_quantity,omitempty"`
+	BsAmount     *float64 `json:"bs_amount,omitempty"`
+	UsdAmount    *float64 `json:"usd_amount,omitempty"`
+}
+
+// Cost returns the price of the whole bundle in the given currency
+func (t PriceTier) Cost(unit Prices, currency Currency) float64 {
+	if t.PaidQuantity != nil {
+		return float64(*t.PaidQuantity) * unit.Amount(currency)
+	}
+	if currency == CurrencyUSD && t.UsdAmount != nil {
+		return *t.UsdAmount
+	}
+	if currency != CurrencyUSD && t.BsAmount != nil {
+		return *t.BsAmount
+	}
+	return float64(t.Quantity) * unit.Amount(currency)
+}
+
+// PriceTable holds the unit prices of a lottery along with its bundles
+type PriceTable struct {
+	Prices
+	Tiers []PriceTier
+}
+
+// Total returns the cheapest price of quantity tickets in the given currency,
+// combining bundles and single tickets as needed
+func (t PriceTable) Total(quantity int, currency Currency) float64 {
+	if quantity <= 0 {
+		return 0
+	}
+
+	// Work in cents so sums of many bundles don't drift
+	cents := func(v float64) int64 { return int64(math.Round(v * 100)) }
+	unit := cents(t.Amount(currency))
+
+	best := make([]int64, quantity+1)
+	for n := 1; n <= quantity; n++ {
+		best[n] = best[n-1] + unit
+		for _, tier := range t.Tiers {
+			if tier.Quantity <= 0 || tier.Quantity > n {
+				continue
+			}
+			cost := best[n-tier.Quantity] + cents(tier.Cost(t.Prices, currency))
+			if cost < best[n] {
+				best[n] = cost
+			}
+		}
+	}
+
+	return float64(best[quantity]) / 100
 }
@@ -0,0 +1,85 @@
+package types
+
+import "testing"
+
+func intPtr(v int) *int           { return &v }
+func floatPtr(v float64) *float64 { return &v }
+
+func TestPriceTable_Total(t *testing.T) {
+	unit := Prices{BsAmount: 150, UsdAmount: 1}
+
+	tests := []struct {
+		name     string
+		tiers    []PriceTier
+		quantity int
+		currency Currency
+		want     float64
+	}{
+		{"flat_bs", nil, 5, CurrencyBs, 750},
+		{"flat_usd", nil, 5, CurrencyUSD, 5},
+		{"zero_quantity", nil, 0, CurrencyUSD, 0},
+		{
+			"ten_for_nine",
+			[]PriceTier{{Quantity: 10, PaidQuantity: intPtr(9)}},
+			10,
+			CurrencyUSD,
+			9,
+		},
+		{
+			"bundle_plus_singles",
+			[]PriceTier{{Quantity: 10, PaidQuantity: intPtr(9)}},
+			13,
+			CurrencyBs,
+			9*150 + 3*150,
+		},
+		{
+			"bundle_repeated",
+			[]PriceTier{{Quantity: 10, PaidQuantity: intPtr(9)}},
+			25,
+			CurrencyUSD,
+			9 + 9 + 5,
+		},
+		{
+			"fixed_bundle",
+			[]PriceTier{{
+				Quantity:  5,
+				BsAmount:  floatPtr(600),
+				UsdAmount: floatPtr(4.5),
+			}},
+			7,
+			CurrencyUSD,
+			4.5 + 2,
+		},
+		{
+			"picks_cheapest_combination",
+			[]PriceTier{
+				{Quantity: 3, PaidQuantity: intPtr(2)},
+				{Quantity: 4, BsAmount: floatPtr(500), UsdAmount: floatPtr(3.5)},
+			},
+			6,
+			CurrencyUSD,
+			4, // two 3-bundles beat a 4-bundle plus two singles
+		},
+		{
+			"bundle_more_expensive_is_ignored",
+			[]PriceTier{{
+				Quantity:  2,
+				BsAmount:  floatPtr(400),
+				UsdAmount: floatPtr(3),
+			}},
+			4,
+			CurrencyBs,
+			600,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := PriceTable{Prices: unit, Tiers: tt.tiers}
+			got := table.Total(tt.quantity, tt.currency)
+			if got != tt.want {
+				t.Fatalf("Total(%d, %s) = %v, want %v", tt.quantity, tt.currency, got, tt.want)
+			}
+		})
+	}
+}
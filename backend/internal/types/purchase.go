@@ -8,16 +8,97 @@ const (
 	StatusPending   PurchaseStatus = "pending"
 	StatusVerified  PurchaseStatus = "verified"
 	StatusCancelled PurchaseStatus = "cancelled"
+	StatusRefunded  PurchaseStatus = "refunded"
 )
 
+// purchaseTransitions lists the statuses each status can be moved to
+var purchaseTransitions = map[PurchaseStatus][]PurchaseStatus{
+	StatusPending:   {StatusVerified, StatusCancelled},
+	StatusVerified:  {StatusPending, StatusCancelled, StatusRefunded},
+	StatusCancelled: {StatusRefunded},
+}
+
+// ReleasesTickets reports whether moving a purchase to s gives its tickets
+// back to the lottery
+func (s PurchaseStatus) ReleasesTickets() bool {
+	return s == StatusCancelled || s == StatusRefunded
+}
+
+// TicketStatus returns the status the tickets of a purchase in status s
+// should have
+func (s PurchaseStatus) TicketStatus() TicketStatus {
+	switch s {
+	case StatusPending:
+		return TicketReserved
+	case StatusVerified:
+		return TicketSold
+	}
+	return TicketAvailable
+}
+
+// CanTransitionTo reports whether a purchase in status s may be moved to next
+func (s PurchaseStatus) CanTransitionTo(next PurchaseStatus) bool {
+	for _, allowed := range purchaseTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+type StatusChangeResult string
+
+const (
+	StatusChangeApplied  StatusChangeResult = "applied"
+	StatusChangeNotFound StatusChangeResult = "not_found"
+	StatusChangeIllegal  StatusChangeResult = "illegal_transition"
+)
+
+// StatusChange is the outcome of moving a single purchase to a new status.
+// Purchase and BuyerEmail are only set when the purchase exists, and
+// Purchase.Status holds the status before the change.
+type StatusChange struct {
+	PurchaseID string
+	Result     StatusChangeResult
+	Purchase   *Purchase
+	BuyerEmail string
+}
+
+// ExpiringPurchase is a pending purchase along with the deadline after which
+// it's cancelled if still unverified
+type ExpiringPurchase struct {
+	Purchase
+	BuyerEmail string
+	Deadline   time.Time
+}
+
+// PurchaseExport is a purchase as listed in the accounting spreadsheets,
+// along with its buyer and tickets
+type PurchaseExport struct {
+	Purchase
+	BuyerName   string
+	BuyerEmail  string
+	BuyerPhone  string
+	LotteryName string
+	Coupon      string
+	Tickets     []int
+}
+
 type Purchase struct {
+	ID                string
 	UserID            string
+	LotteryID         string
 	Quantity          int
 	MontoBs           float64
 	MontoUSD          float64
 	PaymentMethod     string
 	TransactionDigits string
 	PaymentScreenshot []byte
+	ScreenshotHash    uint64
 	Status            PurchaseStatus
+	DuplicateOf       *string
+	PriceID           *string
+	AmountMismatch    bool
+	BonusTickets      int
 	CreatedAt         time.Time
 }
@@ -0,0 +1,23 @@
+package types
+
+import "testing"
+
+func TestPurchaseStatus_TicketStatus(t *testing.T) {
+	tests := []struct {
+		status PurchaseStatus
+		want   TicketStatus
+	}{
+		{StatusPending, TicketReserved},
+		{StatusVerified, TicketSold},
+		{StatusCancelled, TicketAvailable},
+		{StatusRefunded, TicketAvailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			if got := tt.status.TicketStatus(); got != tt.want {
+				t.Fatalf("TicketStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
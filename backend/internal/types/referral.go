@@ -0,0 +1,12 @@
+package types
+
+// ReferralReward records the free tickets a referrer got for the first
+// verified purchase of a user they referred
+type ReferralReward struct {
+	ID            string
+	ReferrerID    string
+	ReferredID    string
+	PurchaseID    string
+	LotteryID     string
+	TicketNumbers []int
+}
@@ -0,0 +1,29 @@
+package types
+
+import "time"
+
+type RefundStatus string
+
+const (
+	RefundPending   RefundStatus = "pending"
+	RefundCompleted RefundStatus = "completed"
+)
+
+// Refund is money sent back to the buyer of a purchase. Completing a Full
+// refund moves the purchase to refunded.
+type Refund struct {
+	ID          string       `json:"id"`
+	PurchaseID  string       `json:"purchase_id"`
+	Amount      float64      `json:"amount"`
+	Currency    Currency     `json:"currency"`
+	Method      string       `json:"method"`
+	Reference   string       `json:"reference"`
+	Reason      string       `json:"reason"`
+	Full        bool         `json:"full"`
+	Proof       []byte       `json:"-"`
+	Status      RefundStatus `json:"status"`
+	CreatedBy   *string      `json:"created_by,omitempty"`
+	CompletedBy *string      `json:"completed_by,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	CompletedAt *time.Time   `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,68 @@
+package types
+
+import (
+	"math"
+	"time"
+)
+
+// SalesBucket holds the tickets claimed by purchases made within one hour or
+// day starting at Period
+type SalesBucket struct {
+	Period   time.Time
+	Sold     int
+	Reserved int
+}
+
+// Tickets counts both sold and reserved tickets
+func (b SalesBucket) Tickets() int {
+	return b.Sold + b.Reserved
+}
+
+// TicketCounts is how many tickets of a lottery are in each state
+type TicketCounts struct {
+	Available int
+	Reserved  int
+	Sold      int
+}
+
+// MovingAverage returns, for each value, the mean of the window values ending
+// at it. The first values average the fewer values available.
+func MovingAverage(values []int, window int) []float64 {
+	if window <= 0 {
+		window = 1
+	}
+
+	averages := make([]float64, len(values))
+	sum := 0
+	for i, v := range values {
+		sum += v
+		if i >= window {
+			sum -= values[i-window]
+		}
+		averages[i] = float64(sum) / float64(min(i+1, window))
+	}
+	return averages
+}
+
+// SellOutAt projects when remaining tickets run out selling rate tickets every
+// interval from now. It returns nil when nothing is selling.
+func SellOutAt(
+	now time.Time,
+	remaining int,
+	rate float64,
+	interval time.Duration,
+) *time.Time {
+	if remaining <= 0 {
+		return &now
+	}
+	if rate <= 0 {
+		return nil
+	}
+
+	periods := float64(remaining) / rate
+	if periods*float64(interval) > math.MaxInt64 {
+		return nil
+	}
+	at := now.Add(time.Duration(periods * float64(interval)))
+	return &at
+}
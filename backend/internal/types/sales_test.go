@@ -0,0 +1,54 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMovingAverage(t *testing.T) {
+	got := MovingAverage([]int{3, 6, 9, 0, 3}, 3)
+	want := []float64{3, 4.5, 6, 5, 4}
+	if len(got) != len(want) {
+		t.Fatalf("MovingAverage() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("MovingAverage() = %v, want %v", got, want)
+		}
+	}
+
+	if got := MovingAverage(nil, 3); len(got) != 0 {
+		t.Errorf("MovingAverage(nil) = %v, want empty", got)
+	}
+	if got := MovingAverage([]int{2, 4}, 0); got[1] != 4 {
+		t.Errorf("MovingAverage(window 0) = %v, want the values", got)
+	}
+}
+
+func TestSellOutAt(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		remaining int
+		rate      float64
+		want      *time.Time
+	}{
+		{"sold_out", 0, 10, &now},
+		{"not_selling", 100, 0, nil},
+		{"selling", 100, 40, ptr(now.Add(60 * time.Hour))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SellOutAt(now, tt.remaining, tt.rate, 24*time.Hour)
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil || !got.Equal(*tt.want):
+				t.Fatalf("SellOutAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
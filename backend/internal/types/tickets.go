@@ -1,10 +1,31 @@
 package types
 
+// TicketPool is the number of tickets of every lottery, numbered 0 to 9999
+const TicketPool = 10000
+
+type TicketStatus string
+
+const (
+	TicketAvailable TicketStatus = "available"
+	// TicketReserved tickets belong to a purchase waiting for payment
+	// verification
+	TicketReserved TicketStatus = "reserved"
+	TicketSold     TicketStatus = "sold"
+)
+
 type Ticket struct {
 	ID         string
 	LotteryID  string
 	Number     string
 	UserID     *string
-	Status     string
+	Status     TicketStatus
 	PurchaseID *string
 }
+
+// TicketAvailability is the share of a lottery's tickets in each state, as
+// percentages
+type TicketAvailability struct {
+	LotteryID string
+	Sold      float64
+	Reserved  float64
+}
@@ -14,4 +14,8 @@ type User struct {
 	Phone    string   `db:"phone"`
 	Role     UserRole `db:"role"`
 	Password string   `db:"password"`
+	// ReferralCode is the code the user shares to refer others, ReferredBy the
+	// id of the user whose code they registered with
+	ReferralCode string  `db:"referral_code"`
+	ReferredBy   *string `db:"referred_by"`
 }
@@ -0,0 +1,41 @@
+package types
+
+import "time"
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "pending"
+	WebhookSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookEndpoint is a URL receiving the events it's subscribed to, signed
+// with its secret. The secret is left out of audit snapshots.
+type WebhookEndpoint struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"-"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is an event sent, or to be sent, to an endpoint along with
+// the outcome of its latest attempt
+type WebhookDelivery struct {
+	ID             string
+	EndpointID     string
+	EventID        string
+	Event          string
+	Payload        []byte
+	Status         WebhookDeliveryStatus
+	Attempts       int
+	ResponseStatus *int
+	ResponseBody   *string
+	Error          *string
+	NextAttemptAt  *time.Time
+	LastAttemptAt  *time.Time
+	CreatedAt      time.Time
+}
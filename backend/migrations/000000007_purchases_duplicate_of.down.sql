@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_purchases_duplicate_of;
+DROP INDEX IF EXISTS idx_purchases_payment_reference;
+ALTER TABLE purchases DROP COLUMN IF EXISTS duplicate_of;
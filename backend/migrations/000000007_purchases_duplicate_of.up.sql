@@ -0,0 +1,10 @@
+ALTER TABLE purchases
+    ADD COLUMN IF NOT EXISTS duplicate_of UUID
+    REFERENCES purchases(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_purchases_payment_reference
+    ON purchases (payment_method, transaction_digits);
+
+CREATE INDEX IF NOT EXISTS idx_purchases_duplicate_of
+    ON purchases (duplicate_of)
+    WHERE duplicate_of IS NOT NULL;
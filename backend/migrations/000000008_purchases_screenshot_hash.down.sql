@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS hamming_distance(BIGINT, BIGINT);
+ALTER TABLE purchases DROP COLUMN IF EXISTS screenshot_hash;
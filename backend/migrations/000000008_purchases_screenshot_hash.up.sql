@@ -0,0 +1,7 @@
+ALTER TABLE purchases ADD COLUMN IF NOT EXISTS screenshot_hash BIGINT;
+
+-- Number of differing bits between two 64-bit perceptual hashes
+CREATE OR REPLACE FUNCTION hamming_distance(a BIGINT, b BIGINT)
+RETURNS INT AS $$
+    SELECT length(replace((a # b)::bit(64)::text, '0', ''))
+$$ LANGUAGE sql IMMUTABLE STRICT;
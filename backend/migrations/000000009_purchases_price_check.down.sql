@@ -0,0 +1,3 @@
+ALTER TABLE purchases
+    DROP COLUMN IF EXISTS amount_mismatch,
+    DROP COLUMN IF EXISTS price_id;
@@ -0,0 +1,3 @@
+ALTER TABLE purchases
+    ADD COLUMN IF NOT EXISTS price_id UUID REFERENCES prices(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS amount_mismatch BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+ALTER TABLE purchases
+    ALTER COLUMN transaction_digits TYPE VARCHAR(6)
+    USING LEFT(transaction_digits, 6);
+
+DROP TABLE IF EXISTS payment_methods;
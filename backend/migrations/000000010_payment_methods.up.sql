@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS payment_methods (
+    id                UUID PRIMARY KEY DEFAULT uuid7(),
+    code              TEXT NOT NULL UNIQUE,
+    display_name      TEXT NOT NULL,
+    currency          TEXT NOT NULL CHECK (currency IN ('bs', 'usd')),
+    account_details   TEXT NOT NULL DEFAULT '',
+    enabled           BOOLEAN NOT NULL DEFAULT TRUE,
+    reference_pattern TEXT NOT NULL DEFAULT '^[0-9]{6}$',
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Seed the methods the landing page has offered so far
+INSERT INTO payment_methods (code, display_name, currency)
+VALUES
+    ('pago movil', 'PAGOMOVIL', 'bs'),
+    ('zelle', 'ZELLE', 'usd')
+ON CONFLICT (code) DO NOTHING;
+
+-- Reference patterns are configurable per method, so the references of
+-- purchases aren't limited to 6 digits anymore
+ALTER TABLE purchases ALTER COLUMN transaction_digits TYPE VARCHAR(64);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    id         UUID PRIMARY KEY DEFAULT uuid7(),
+    rate       NUMERIC(14,4) NOT NULL CHECK (rate > 0),
+    source     TEXT NOT NULL DEFAULT 'manual',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_created_at
+    ON exchange_rates (created_at DESC);
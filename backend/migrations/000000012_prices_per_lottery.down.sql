@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_prices_lottery_effective_from;
+ALTER TABLE prices
+    DROP COLUMN IF EXISTS created_by,
+    DROP COLUMN IF EXISTS effective_from,
+    DROP COLUMN IF EXISTS lottery_id;
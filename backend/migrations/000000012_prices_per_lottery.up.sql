@@ -0,0 +1,18 @@
+ALTER TABLE prices
+    ADD COLUMN IF NOT EXISTS lottery_id UUID REFERENCES lotteries(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS effective_from TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS created_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- Existing prices belong to the lottery running when they were set
+UPDATE prices
+SET lottery_id = (SELECT id FROM lotteries WHERE active = TRUE LIMIT 1)
+WHERE lottery_id IS NULL;
+
+UPDATE prices SET effective_from = created_at WHERE effective_from IS NULL;
+
+ALTER TABLE prices
+    ALTER COLUMN effective_from SET DEFAULT NOW(),
+    ALTER COLUMN effective_from SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_prices_lottery_effective_from
+    ON prices (lottery_id, effective_from DESC);
@@ -0,0 +1,16 @@
+-- Volume pricing: a bundle of `quantity` tickets either costs `paid_quantity`
+-- unit prices ("10 for the price of 9") or a fixed amount in each currency
+CREATE TABLE IF NOT EXISTS price_tiers (
+    id            UUID PRIMARY KEY DEFAULT uuid7(),
+    lottery_id    UUID NOT NULL REFERENCES lotteries(id) ON DELETE CASCADE,
+    quantity      INT NOT NULL CHECK (quantity > 1),
+    paid_quantity INT CHECK (paid_quantity > 0 AND paid_quantity < quantity),
+    bs_amount     NUMERIC(12,2) CHECK (bs_amount > 0),
+    usd_amount    NUMERIC(12,2) CHECK (usd_amount > 0),
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (lottery_id, quantity),
+    CHECK (
+        (paid_quantity IS NOT NULL AND bs_amount IS NULL AND usd_amount IS NULL)
+        OR (paid_quantity IS NULL AND bs_amount IS NOT NULL AND usd_amount IS NOT NULL)
+    )
+);
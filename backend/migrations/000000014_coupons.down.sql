@@ -0,0 +1,4 @@
+ALTER TABLE purchases DROP COLUMN IF EXISTS bonus_tickets;
+
+DROP TABLE IF EXISTS coupon_redemptions;
+DROP TABLE IF EXISTS coupons;
@@ -0,0 +1,37 @@
+-- Promo codes: a percentage off, a fixed USD amount off (converted to Bs with
+-- the lottery prices) or extra tickets on top of the ones paid for
+CREATE TABLE IF NOT EXISTS coupons (
+    id             UUID PRIMARY KEY DEFAULT uuid7(),
+    code           TEXT NOT NULL UNIQUE,
+    type           TEXT NOT NULL
+        CHECK (type IN ('percent', 'fixed', 'bonus_tickets')),
+    value          NUMERIC(12,2) NOT NULL CHECK (value > 0),
+    max_uses       INT CHECK (max_uses > 0),
+    per_user_limit INT CHECK (per_user_limit > 0),
+    starts_at      TIMESTAMPTZ,
+    ends_at        TIMESTAMPTZ,
+    lottery_id     UUID REFERENCES lotteries(id) ON DELETE CASCADE,
+    enabled        BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (type != 'percent' OR value <= 100),
+    CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at)
+);
+
+-- A redemption only counts towards the coupon limits until it's released by
+-- the cancellation of its purchase
+CREATE TABLE IF NOT EXISTS coupon_redemptions (
+    id          UUID PRIMARY KEY DEFAULT uuid7(),
+    coupon_id   UUID NOT NULL REFERENCES coupons(id) ON DELETE CASCADE,
+    purchase_id UUID NOT NULL UNIQUE REFERENCES purchases(id) ON DELETE CASCADE,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    released_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_coupon_user
+    ON coupon_redemptions (coupon_id, user_id)
+    WHERE released_at IS NULL;
+
+ALTER TABLE purchases
+    ADD COLUMN IF NOT EXISTS bonus_tickets INT NOT NULL DEFAULT 0;
@@ -0,0 +1,27 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS referral_code TEXT UNIQUE,
+    ADD COLUMN IF NOT EXISTS referred_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- Give the existing users a code too
+UPDATE users
+SET referral_code = UPPER(SUBSTRING(MD5(id::text) FOR 8))
+WHERE referral_code IS NULL;
+
+ALTER TABLE users ALTER COLUMN referral_code SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_referred_by ON users (referred_by);
+
+-- A referred user earns their referrer a reward only once, with their first
+-- verified purchase. Reward tickets don't belong to any purchase.
+CREATE TABLE IF NOT EXISTS referral_rewards (
+    id             UUID PRIMARY KEY DEFAULT uuid7(),
+    referrer_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    referred_id    UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    purchase_id    UUID REFERENCES purchases(id) ON DELETE SET NULL,
+    lottery_id     UUID NOT NULL REFERENCES lotteries(id) ON DELETE CASCADE,
+    ticket_numbers INT[] NOT NULL DEFAULT '{}',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_referral_rewards_referrer
+    ON referral_rewards (referrer_id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_purchases_lottery_user;
+
+ALTER TABLE purchases DROP COLUMN IF EXISTS lottery_id;
+
+ALTER TABLE lotteries
+    DROP COLUMN IF EXISTS max_per_user,
+    DROP COLUMN IF EXISTS max_per_purchase,
+    DROP COLUMN IF EXISTS min_per_purchase;
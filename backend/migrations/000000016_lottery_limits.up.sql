@@ -0,0 +1,30 @@
+-- Quantity limits of each lottery. Maximums are optional, the minimum keeps
+-- the 2 tickets the purchase form has always required.
+ALTER TABLE lotteries
+    ADD COLUMN IF NOT EXISTS min_per_purchase INT NOT NULL DEFAULT 2
+        CHECK (min_per_purchase > 0),
+    ADD COLUMN IF NOT EXISTS max_per_purchase INT
+        CHECK (max_per_purchase >= min_per_purchase),
+    ADD COLUMN IF NOT EXISTS max_per_user INT
+        CHECK (max_per_user >= min_per_purchase);
+
+-- Purchases now know their lottery so per user totals can be counted
+ALTER TABLE purchases
+    ADD COLUMN IF NOT EXISTS lottery_id UUID REFERENCES lotteries(id);
+
+UPDATE purchases p
+SET lottery_id = t.lottery_id
+FROM (
+    SELECT DISTINCT ON (purchase_id) purchase_id, lottery_id
+    FROM tickets
+    WHERE purchase_id IS NOT NULL
+) t
+WHERE t.purchase_id = p.id AND p.lottery_id IS NULL;
+
+-- Cancelled purchases released their tickets, assume the running lottery
+UPDATE purchases
+SET lottery_id = (SELECT id FROM lotteries WHERE active = TRUE LIMIT 1)
+WHERE lottery_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_purchases_lottery_user
+    ON purchases (lottery_id, user_id);
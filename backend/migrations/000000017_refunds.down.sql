@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS refunds;
+
+UPDATE purchases SET status = 'cancelled' WHERE status = 'refunded';
+
+ALTER TABLE purchases DROP CONSTRAINT IF EXISTS purchases_status_check;
+ALTER TABLE purchases ADD CONSTRAINT purchases_status_check
+    CHECK (status IN ('pending', 'verified', 'cancelled'));
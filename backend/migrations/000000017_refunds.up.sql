@@ -0,0 +1,27 @@
+ALTER TABLE purchases DROP CONSTRAINT IF EXISTS purchases_status_check;
+ALTER TABLE purchases ADD CONSTRAINT purchases_status_check
+    CHECK (status IN ('pending', 'verified', 'cancelled', 'refunded'));
+
+-- Money sent back to a buyer. A full refund moves its purchase to refunded
+-- once completed, a partial one (over-payments) leaves the purchase as is.
+CREATE TABLE IF NOT EXISTS refunds (
+    id           UUID PRIMARY KEY DEFAULT uuid7(),
+    purchase_id  UUID NOT NULL REFERENCES purchases(id) ON DELETE CASCADE,
+    amount       NUMERIC(12,2) NOT NULL CHECK (amount > 0),
+    currency     TEXT NOT NULL CHECK (currency IN ('bs', 'usd')),
+    method       TEXT NOT NULL,
+    reference    TEXT NOT NULL DEFAULT '',
+    reason       TEXT NOT NULL DEFAULT '',
+    full_refund  BOOLEAN NOT NULL DEFAULT FALSE,
+    proof        BYTEA,
+    status       TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'completed')),
+    created_by   UUID REFERENCES users(id) ON DELETE SET NULL,
+    completed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_purchase_id ON refunds (purchase_id);
+CREATE INDEX IF NOT EXISTS idx_refunds_status_created_at
+    ON refunds (status, created_at DESC);
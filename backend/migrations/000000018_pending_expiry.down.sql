@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_purchases_pending_created_at;
+
+ALTER TABLE purchases DROP COLUMN IF EXISTS expiry_warned_at;
+
+ALTER TABLE lotteries DROP COLUMN IF EXISTS pending_expiry_hours;
@@ -0,0 +1,12 @@
+-- Hours a purchase may stay pending before it's cancelled automatically,
+-- NULL keeps pending purchases forever
+ALTER TABLE lotteries
+    ADD COLUMN IF NOT EXISTS pending_expiry_hours INT
+        CHECK (pending_expiry_hours > 0);
+
+ALTER TABLE purchases
+    ADD COLUMN IF NOT EXISTS expiry_warned_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_purchases_pending_created_at
+    ON purchases (created_at)
+    WHERE status = 'pending';
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_tickets_lottery_status;
+
+UPDATE tickets SET status = 'sold' WHERE status = 'reserved';
+
+ALTER TABLE tickets DROP CONSTRAINT IF EXISTS tickets_status_check;
+ALTER TABLE tickets ADD CONSTRAINT tickets_status_check
+    CHECK (status IN ('available', 'sold', 'held'));
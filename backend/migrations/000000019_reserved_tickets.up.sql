@@ -0,0 +1,13 @@
+ALTER TABLE tickets DROP CONSTRAINT IF EXISTS tickets_status_check;
+ALTER TABLE tickets ADD CONSTRAINT tickets_status_check
+    CHECK (status IN ('available', 'reserved', 'sold', 'held'));
+
+-- Tickets of purchases still waiting for payment verification are reserved,
+-- not sold
+UPDATE tickets t
+SET status = 'reserved', reserved_at = COALESCE(t.reserved_at, p.created_at)
+FROM purchases p
+WHERE p.id = t.purchase_id AND p.status = 'pending' AND t.status = 'sold';
+
+CREATE INDEX IF NOT EXISTS idx_tickets_lottery_status
+    ON tickets (lottery_id, status);
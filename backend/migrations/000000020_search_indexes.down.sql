@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_users_name_fts;
+DROP INDEX IF EXISTS idx_purchases_transaction_digits_trgm;
+DROP INDEX IF EXISTS idx_users_phone_trgm;
+DROP INDEX IF EXISTS idx_users_email_trgm;
+DROP INDEX IF EXISTS idx_users_name_trgm;
+
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,15 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Trigram indexes back the fuzzy and partial matches of the admin search
+CREATE INDEX IF NOT EXISTS idx_users_name_trgm
+    ON users USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_email_trgm
+    ON users USING GIN (email gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_phone_trgm
+    ON users USING GIN (phone gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_purchases_transaction_digits_trgm
+    ON purchases USING GIN (transaction_digits gin_trgm_ops);
+
+-- Full-text matching of names regardless of word order
+CREATE INDEX IF NOT EXISTS idx_users_name_fts
+    ON users USING GIN (to_tsvector('simple', name));
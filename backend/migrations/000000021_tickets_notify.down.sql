@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS tickets_changed ON tickets;
+DROP FUNCTION IF EXISTS notify_tickets_changed();
@@ -0,0 +1,42 @@
+-- Notifies the tickets_changed channel whenever tickets change status, with
+-- the numbers taken and released by the statement. Large changes only send
+-- the lottery to stay under the notification payload limit.
+CREATE OR REPLACE FUNCTION notify_tickets_changed() RETURNS trigger AS $$
+DECLARE
+    lottery  TEXT;
+    taken    JSON;
+    released JSON;
+    payload  TEXT;
+BEGIN
+    SELECT MIN(n.lottery_id::text),
+        COALESCE(JSON_AGG(n.number ORDER BY n.number)
+            FILTER (WHERE o.status = 'available'), '[]'),
+        COALESCE(JSON_AGG(n.number ORDER BY n.number)
+            FILTER (WHERE n.status = 'available'), '[]')
+    INTO lottery, taken, released
+    FROM new_rows n
+    JOIN old_rows o ON o.id = n.id
+    WHERE o.status IS DISTINCT FROM n.status;
+
+    IF lottery IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    payload := JSON_BUILD_OBJECT(
+        'lotteryId', lottery,
+        'taken', taken,
+        'released', released
+    )::text;
+    IF OCTET_LENGTH(payload) > 7000 THEN
+        payload := JSON_BUILD_OBJECT('lotteryId', lottery)::text;
+    END IF;
+
+    PERFORM PG_NOTIFY('tickets_changed', payload);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tickets_changed
+    AFTER UPDATE ON tickets
+    REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_tickets_changed();
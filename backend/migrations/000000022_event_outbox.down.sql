@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS event_outbox_inserted ON event_outbox;
+DROP FUNCTION IF EXISTS notify_event_outbox();
+DROP TABLE IF EXISTS event_outbox;
@@ -0,0 +1,30 @@
+-- Domain events stored in the same transaction as the change they describe,
+-- relayed to the subscribers once committed. A row is claimed by pushing
+-- locked_until forward and done once dispatched_at is set.
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id            UUID PRIMARY KEY DEFAULT uuid7(),
+    name          TEXT NOT NULL,
+    payload       JSONB NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    attempts      INT NOT NULL DEFAULT 0,
+    locked_until  TIMESTAMPTZ,
+    dispatched_at TIMESTAMPTZ,
+    last_error    TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_pending
+    ON event_outbox (id)
+    WHERE dispatched_at IS NULL;
+
+-- Wakes the relay up when events are committed instead of waiting for its
+-- next poll
+CREATE OR REPLACE FUNCTION notify_event_outbox() RETURNS trigger AS $$
+BEGIN
+    PERFORM PG_NOTIFY('event_outbox', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER event_outbox_inserted
+    AFTER INSERT ON event_outbox
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_event_outbox();
@@ -0,0 +1,39 @@
+-- Admin managed endpoints receiving the domain events they subscribe to
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id          UUID PRIMARY KEY DEFAULT uuid7(),
+    url         TEXT NOT NULL,
+    secret      TEXT NOT NULL,
+    events      TEXT[] NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    active      BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Every event sent to an endpoint, with the outcome of its latest attempt.
+-- Pending deliveries are retried from next_attempt_at on.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              UUID PRIMARY KEY DEFAULT uuid7(),
+    endpoint_id     UUID NOT NULL
+        REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    event_id        TEXT NOT NULL,
+    event           TEXT NOT NULL,
+    payload         JSONB NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts        INT NOT NULL DEFAULT 0,
+    response_status INT,
+    response_body   TEXT,
+    error           TEXT,
+    next_attempt_at TIMESTAMPTZ,
+    last_attempt_at TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (endpoint_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+    ON webhook_deliveries (next_attempt_at)
+    WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint
+    ON webhook_deliveries (endpoint_id, created_at DESC);
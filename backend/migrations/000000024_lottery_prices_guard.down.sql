@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS lottery_prices_on_activation ON lotteries;
+DROP FUNCTION IF EXISTS seed_lottery_prices();
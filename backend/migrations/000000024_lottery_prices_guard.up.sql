@@ -0,0 +1,31 @@
+-- A lottery can't go live without prices: activating one that has none in
+-- effect carries over the latest prices of the other lotteries, and fails
+-- when there's nothing to carry over
+CREATE OR REPLACE FUNCTION seed_lottery_prices() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM prices
+        WHERE lottery_id = NEW.id AND effective_from <= NOW()
+    ) THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO prices (lottery_id, bs_amount, usd_amount)
+    SELECT NEW.id, bs_amount, usd_amount
+    FROM prices
+    WHERE lottery_id <> NEW.id AND effective_from <= NOW()
+    ORDER BY effective_from DESC, created_at DESC
+    LIMIT 1;
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'lottery % has no prices to activate with', NEW.id
+            USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER lottery_prices_on_activation
+    AFTER INSERT OR UPDATE OF active ON lotteries
+    FOR EACH ROW WHEN (NEW.active)
+    EXECUTE FUNCTION seed_lottery_prices();
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_prices_unannounced;
+ALTER TABLE prices DROP COLUMN IF EXISTS announced_at;
@@ -0,0 +1,9 @@
+-- Scheduled prices are announced once they come into effect, the ones in
+-- effect already count as announced
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS announced_at TIMESTAMPTZ;
+
+UPDATE prices SET announced_at = created_at WHERE effective_from <= NOW();
+
+CREATE INDEX IF NOT EXISTS idx_prices_unannounced
+    ON prices (effective_from)
+    WHERE announced_at IS NULL;
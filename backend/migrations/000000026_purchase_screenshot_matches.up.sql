@@ -0,0 +1,25 @@
+-- Purchases whose screenshots look alike, stored both ways when a purchase is
+-- created instead of comparing every pair of hashes on each listing. Hashes
+-- with fewer than 8 bits set or unset come from blank or uniform pictures
+-- and aren't matched.
+CREATE TABLE IF NOT EXISTS purchase_screenshot_matches (
+    purchase_id UUID NOT NULL REFERENCES purchases(id) ON DELETE CASCADE,
+    similar_id  UUID NOT NULL REFERENCES purchases(id) ON DELETE CASCADE,
+    PRIMARY KEY (purchase_id, similar_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_screenshot_matches_similar
+    ON purchase_screenshot_matches (similar_id);
+
+WITH hashed AS (
+    SELECT id, screenshot_hash
+    FROM purchases
+    WHERE screenshot_hash IS NOT NULL
+        AND hamming_distance(screenshot_hash, 0) BETWEEN 8 AND 56
+)
+INSERT INTO purchase_screenshot_matches (purchase_id, similar_id)
+SELECT a.id, b.id
+FROM hashed a
+JOIN hashed b ON a.id <> b.id
+    AND hamming_distance(a.screenshot_hash, b.screenshot_hash) <= 6
+ON CONFLICT DO NOTHING;
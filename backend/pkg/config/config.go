@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -33,6 +34,11 @@ type ServiceOpts struct {
 	UseSecureCookie bool `env:"COOKIE_SECURE" envDefault:"false"`
 	JwtOpts         JwtOpts
 	Email           EmailOpts
+	Purchase        PurchaseOpts
+	Price           PriceOpts
+	Referral        ReferralOpts
+	Events          EventOpts
+	Webhook         WebhookOpts
 }
 
 type JwtOpts struct {
@@ -47,6 +53,72 @@ type EmailOpts struct {
 	EmailURL       string `env:"EMAIL_URL" envDefault:"https://smtp.maileroo.com/api/v2/emails"`
 }
 
+// Policies for suspicious purchases: keep them marked for review or refuse them
+const (
+	PolicyFlag   = "flag"
+	PolicyReject = "reject"
+)
+
+type PurchaseOpts struct {
+	// DuplicatePolicy decides what happens to a purchase reusing the payment
+	// reference of another one: "flag" stores it marked, "reject" refuses it
+	DuplicatePolicy string `env:"PURCHASE_DUPLICATE_POLICY" envDefault:"flag"`
+	// AmountMismatchPolicy decides what happens to a purchase whose amount
+	// doesn't match quantity x current price: "flag" or "reject". It flags
+	// by default since the web form doesn't price bundles or coupons yet,
+	// which means underpaid purchases are stored and only marked for review
+	// until it does; see the README.
+	AmountMismatchPolicy string `env:"PURCHASE_AMOUNT_MISMATCH_POLICY" envDefault:"flag"`
+	// ExpiryInterval is how often pending purchases past the deadline of
+	// their lottery are cancelled, ExpiryWarning how long before the deadline
+	// buyers get a reminder
+	ExpiryInterval time.Duration `env:"PURCHASE_EXPIRY_INTERVAL" envDefault:"5m"`
+	ExpiryWarning  time.Duration `env:"PURCHASE_EXPIRY_WARNING" envDefault:"6h"`
+}
+
+type PriceOpts struct {
+	// DeriveBs computes the Bs price as USD price x latest exchange rate
+	DeriveBs bool `env:"PRICE_DERIVE_BS" envDefault:"false"`
+	// RoundingStep and RoundingMode ("nearest", "up" or "down") control how
+	// the derived Bs price is rounded
+	RoundingStep float64 `env:"PRICE_BS_ROUNDING_STEP" envDefault:"0.01"`
+	RoundingMode string  `env:"PRICE_BS_ROUNDING_MODE" envDefault:"nearest"`
+	// ScheduleInterval is how often prices scheduled for later are checked
+	// to announce the ones that came into effect
+	ScheduleInterval time.Duration `env:"PRICE_SCHEDULE_INTERVAL" envDefault:"1m"`
+	Rates            RateOpts
+}
+
+type RateOpts struct {
+	// Provider is where the daily rate is fetched from: "manual", "file" or "http"
+	Provider string `env:"RATE_PROVIDER" envDefault:"manual"`
+	File     string `env:"RATE_FILE"`
+	URL      string `env:"RATE_URL"`
+	// Field is the dot separated path of the rate in the HTTP JSON response
+	Field string `env:"RATE_FIELD" envDefault:"rate"`
+}
+
+type ReferralOpts struct {
+	// RewardTickets is how many free tickets a referrer gets once the first
+	// purchase of a user they referred is verified, 0 disables the rewards
+	RewardTickets int `env:"REFERRAL_REWARD_TICKETS" envDefault:"1"`
+}
+
+type EventOpts struct {
+	// OutboxInterval is how often the outbox is polled for events to
+	// dispatch, on top of the notifications sent when they're committed
+	OutboxInterval time.Duration `env:"EVENTS_OUTBOX_INTERVAL" envDefault:"5s"`
+}
+
+type WebhookOpts struct {
+	// MaxAttempts is how many times a delivery is tried before it's given up
+	MaxAttempts int `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"6"`
+	// RetryInterval is how often the failed deliveries due are retried
+	RetryInterval time.Duration `env:"WEBHOOK_RETRY_INTERVAL" envDefault:"30s"`
+	// Timeout bounds each delivery request
+	Timeout time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+}
+
 type CollectorOpts struct {
 	CollectorEnv             string `env:"APP_ENV" envDefault:"development"`
 	CollectorExporter        string `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
@@ -63,6 +135,9 @@ func NewConfig() (*Config, error) {
 	var err error
 	once.Do(func() {
 		err = env.Parse(cfg)
+		if err == nil {
+			err = cfg.validate()
+		}
 	})
 	if err != nil {
 		return nil, err
@@ -70,3 +145,36 @@ func NewConfig() (*Config, error) {
 
 	return cfg, err
 }
+
+// validate fails on the options parsed fine but holding values the services
+// don't understand
+func (c *Config) validate() error {
+	policies := []struct{ name, value string }{
+		{"PURCHASE_DUPLICATE_POLICY", c.Service.Purchase.DuplicatePolicy},
+		{"PURCHASE_AMOUNT_MISMATCH_POLICY", c.Service.Purchase.AmountMismatchPolicy},
+	}
+	for _, p := range policies {
+		if p.value != PolicyFlag && p.value != PolicyReject {
+			return fmt.Errorf(
+				"%s must be %q or %q, got %q",
+				p.name,
+				PolicyFlag,
+				PolicyReject,
+				p.value,
+			)
+		}
+	}
+
+	switch c.Service.Price.RoundingMode {
+	case "nearest", "up", "down":
+	default:
+		return fmt.Errorf(
+			"PRICE_BS_ROUNDING_MODE must be %q, %q or %q, got %q",
+			"nearest",
+			"up",
+			"down",
+			c.Service.Price.RoundingMode,
+		)
+	}
+	return nil
+}
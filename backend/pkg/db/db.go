@@ -22,6 +22,13 @@ type DB interface {
 	Close()
 }
 
+// Listener is implemented by databases able to push notifications
+type Listener interface {
+	// Listen calls fn with the payload of every notification sent on channel
+	// until ctx is done or the connection fails
+	Listen(ctx context.Context, channel string, fn func(payload string)) error
+}
+
 type Tx interface {
 	Query(ctx context.Context, query string, args ...any) (Rows, error)
 	QueryRow(ctx context.Context, query string, args ...any) Row
@@ -3,11 +3,14 @@ package db
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
 
 	"rifa/backend/pkg/config"
+
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type fakeDB struct {
@@ -183,3 +186,21 @@ func TestConnect_Singleton_Concurrency(t *testing.T) {
 		}
 	}
 }
+
+func TestIsUniqueViolation(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"unique":  {&pgconn.PgError{Code: "23505"}, true},
+		"wrapped": {fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505"}), true},
+		"other":   {&pgconn.PgError{Code: "23503"}, false},
+		"plain":   {errors.New("23505"), false},
+		"nil":     {nil, false},
+	}
+	for name, tt := range tests {
+		if got := IsUniqueViolation(tt.err); got != tt.want {
+			t.Errorf("%s: IsUniqueViolation() = %v, want %v", name, got, tt.want)
+		}
+	}
+}
@@ -2,12 +2,25 @@ package db
 
 import (
 	"context"
+	"errors"
 
 	"github.com/exaring/otelpgx"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// uniqueViolation is the Postgres error code of a row breaking a unique
+// constraint
+const uniqueViolation = "23505"
+
+// IsUniqueViolation reports whether err is Postgres refusing a row that
+// breaks a unique constraint
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
 type postgresDriver struct{}
 
 func NewPostgresDriver() Driver { return &postgresDriver{} }
@@ -58,6 +71,36 @@ func (p *PGXPool) BeginTx(ctx context.Context) (Tx, error) {
 	return &pgxTx{tx: tx}, nil
 }
 
+// Listen holds a pool connection for as long as it listens
+func (p *PGXPool) Listen(
+	ctx context.Context,
+	channel string,
+	fn func(payload string),
+) error {
+	conn, err := p.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// The connection goes back to the pool, so it must stop listening
+		_, _ = conn.Exec(context.Background(), "UNLISTEN *")
+		conn.Release()
+	}()
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize())
+	if err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		fn(notification.Payload)
+	}
+}
+
 func (p *PGXPool) Close() {
 	p.Pool.Close()
 }
@@ -0,0 +1,157 @@
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"rifa/backend/pkg/config"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// RateProvider fetches the current USD/VES exchange rate (Bs per dollar)
+type RateProvider interface {
+	// Name identifies the provider as the source of the recorded rates
+	Name() string
+	Rate(ctx context.Context) (float64, error)
+}
+
+// NewProvider builds the provider configured in opts. It returns nil for the
+// "manual" provider, where rates are only recorded by an admin.
+func NewProvider(opts config.RateOpts) (RateProvider, error) {
+	switch opts.Provider {
+	case "", "manual":
+		return nil, nil
+	case "file":
+		if opts.File == "" {
+			return nil, errors.New("RATE_FILE is required for the file provider")
+		}
+		return NewFileProvider(opts.File), nil
+	case "http":
+		if opts.URL == "" {
+			return nil, errors.New("RATE_URL is required for the http provider")
+		}
+		return NewHTTPProvider(opts.URL, opts.Field, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown rate provider %q", opts.Provider)
+	}
+}
+
+type fileProvider struct {
+	path string
+}
+
+// NewFileProvider reads the rate from a text file holding a single number,
+// so it can be updated by hand or by an external script.
+func NewFileProvider(path string) RateProvider {
+	return &fileProvider{path: path}
+}
+
+func (p *fileProvider) Name() string { return "file" }
+
+func (p *fileProvider) Rate(_ context.Context) (float64, error) {
+	content, err := os.ReadFile(p.path)
+	if err != nil {
+		return 0, fmt.Errorf("read rate file: %w", err)
+	}
+	return parseRate(strings.TrimSpace(string(content)))
+}
+
+type httpProvider struct {
+	url    string
+	field  string
+	client *http.Client
+}
+
+// NewHTTPProvider fetches a JSON document from url and reads the rate at the
+// dot separated field path (e.g. "monitors.bcv.price"). A nil client uses a
+// traced client with a 10 seconds timeout.
+func NewHTTPProvider(url, field string, client *http.Client) RateProvider {
+	if client == nil {
+		client = &http.Client{
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+			Timeout:   10 * time.Second,
+		}
+	}
+	if field == "" {
+		field = "rate"
+	}
+	return &httpProvider{url: url, field: field, client: client}
+}
+
+func (p *httpProvider) Name() string { return "http" }
+
+func (p *httpProvider) Rate(ctx context.Context) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create rate request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch rate: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("failed to close body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("rate request failed: status %s", resp.Status)
+	}
+
+	var doc any
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&doc)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode rate response: %w", err)
+	}
+
+	for _, key := range strings.Split(p.field, ".") {
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return 0, fmt.Errorf("rate field %q not found", p.field)
+		}
+		doc, ok = obj[key]
+		if !ok {
+			return 0, fmt.Errorf("rate field %q not found", p.field)
+		}
+	}
+
+	switch v := doc.(type) {
+	case float64:
+		return validRate(v)
+	case string:
+		return parseRate(v)
+	default:
+		return 0, fmt.Errorf("rate field %q is not a number", p.field)
+	}
+}
+
+// parseRate accepts both "36.52" and the local "36,52" notation
+func parseRate(s string) (float64, error) {
+	rate, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q: %w", s, err)
+	}
+	return validRate(rate)
+}
+
+func validRate(rate float64) (float64, error) {
+	// NaN fails every comparison, so it's refused by asking for rate > 0
+	if !(rate > 0) || math.IsInf(rate, 0) {
+		return 0, fmt.Errorf("invalid rate %v: must be a positive number", rate)
+	}
+	return rate, nil
+}
@@ -0,0 +1,156 @@
+package rates
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"rifa/backend/pkg/config"
+)
+
+func TestFileProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    float64
+		wantErr bool
+	}{
+		{"plain", "36.52", 36.52, false},
+		{"trailing_newline", "36.52\n", 36.52, false},
+		{"comma_decimal", "36,52", 36.52, false},
+		{"garbage", "abc", 0, true},
+		{"zero", "0", 0, true},
+		{"negative", "-1", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rate.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := NewFileProvider(path).Rate(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got rate %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Rate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Rate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileProvider_MissingFile(t *testing.T) {
+	p := NewFileProvider(filepath.Join(t.TempDir(), "missing.txt"))
+	if _, err := p.Rate(context.Background()); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		status  int
+		body    string
+		want    float64
+		wantErr bool
+	}{
+		{"top_level", "rate", http.StatusOK, `{"rate": 36.5}`, 36.5, false},
+		{
+			"nested",
+			"monitors.bcv.price",
+			http.StatusOK,
+			`{"monitors": {"bcv": {"price": 40.12}}}`,
+			40.12,
+			false,
+		},
+		{"string_value", "rate", http.StatusOK, `{"rate": "38,75"}`, 38.75, false},
+		{"nan", "rate", http.StatusOK, `{"rate": "NaN"}`, 0, true},
+		{"infinite", "rate", http.StatusOK, `{"rate": "Inf"}`, 0, true},
+		{"missing_field", "rate", http.StatusOK, `{"price": 1}`, 0, true},
+		{"not_an_object", "a.b", http.StatusOK, `{"a": 3}`, 0, true},
+		{"bad_type", "rate", http.StatusOK, `{"rate": true}`, 0, true},
+		{"bad_json", "rate", http.StatusOK, `not json`, 0, true},
+		{"server_error", "rate", http.StatusBadGateway, `{}`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(tt.status)
+					_, _ = w.Write([]byte(tt.body))
+				},
+			))
+			t.Cleanup(srv.Close)
+
+			p := NewHTTPProvider(srv.URL, tt.field, srv.Client())
+			got, err := p.Rate(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got rate %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Rate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Rate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     config.RateOpts
+		wantName string
+		wantErr  bool
+	}{
+		{"manual", config.RateOpts{Provider: "manual"}, "", false},
+		{"empty_is_manual", config.RateOpts{}, "", false},
+		{"file", config.RateOpts{Provider: "file", File: "rate.txt"}, "file", false},
+		{"file_without_path", config.RateOpts{Provider: "file"}, "", true},
+		{"http", config.RateOpts{Provider: "http", URL: "http://x"}, "http", false},
+		{"http_without_url", config.RateOpts{Provider: "http"}, "", true},
+		{"unknown", config.RateOpts{Provider: "carrier-pigeon"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewProvider(tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewProvider() error = %v", err)
+			}
+			if tt.wantName == "" {
+				if p != nil {
+					t.Fatalf("expected no provider, got %q", p.Name())
+				}
+				return
+			}
+			if p == nil || p.Name() != tt.wantName {
+				t.Fatalf("expected provider %q, got %v", tt.wantName, p)
+			}
+		})
+	}
+}
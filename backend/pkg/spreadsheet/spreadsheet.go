@@ -0,0 +1,53 @@
+// Package spreadsheet writes tabular data as CSV or XLSX one row at a time,
+// so exports never hold the whole table in memory.
+package spreadsheet
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+type Format string
+
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+// timeLayout is how time.Time cells are written in both formats
+const timeLayout = "2006-01-02 15:04:05"
+
+// ContentType returns the MIME type of files in format f
+func (f Format) ContentType() string {
+	if f == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Writer writes rows of cells. Cells may be strings, integers, floats,
+// booleans, time.Time or nil, anything else is written with fmt.Sprint.
+// Close must be called to flush the file.
+type Writer interface {
+	WriteRow(cells ...any) error
+	Close() error
+}
+
+// NewWriter returns a Writer of the given format writing to w
+func NewWriter(w io.Writer, format Format) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w), nil
+	case XLSX:
+		return newXLSXWriter(w)
+	}
+	return nil, fmt.Errorf("unknown spreadsheet format %q", format)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(timeLayout)
+}
@@ -0,0 +1,137 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+)
+
+var created = time.Date(2025, 3, 4, 15, 30, 0, 0, time.UTC)
+
+func writeRows(t *testing.T, format Format, rows [][]any) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row...); err != nil {
+			t.Fatalf("WriteRow() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVWriter(t *testing.T) {
+	got := writeRows(t, CSV, [][]any{
+		{"Nombre", "Cantidad", "Monto", "Fecha"},
+		{"Ana, María", 3, 4.5, created},
+		{"=HYPERLINK(\"x\")", nil, true, time.Time{}},
+	})
+
+	want := "Nombre,Cantidad,Monto,Fecha\n" +
+		"\"Ana, María\",3,4.5,2025-03-04 15:30:00\n" +
+		"\"'=HYPERLINK(\"\"x\"\")\",,true,\n"
+	if string(got) != want {
+		t.Fatalf("csv = %q, want %q", got, want)
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	got := writeRows(t, XLSX, [][]any{
+		{"Nombre", "Cantidad"},
+		{"<Ana & María>", 3, 4.5, false, created, nil},
+	})
+
+	z, err := zip.NewReader(bytes.NewReader(got), int64(len(got)))
+	if err != nil {
+		t.Fatalf("output is not a zip file: %v", err)
+	}
+
+	parts := map[string]*zip.File{}
+	for _, f := range z.File {
+		parts[f.Name] = f
+	}
+	for _, name := range []string{
+		"[Content_Types].xml",
+		"_rels/.rels",
+		"xl/workbook.xml",
+		"xl/_rels/workbook.xml.rels",
+		"xl/worksheets/sheet1.xml",
+	} {
+		if parts[name] == nil {
+			t.Fatalf("missing part %q", name)
+		}
+	}
+
+	f, err := parts["xl/worksheets/sheet1.xml"].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	raw, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(raw, &sheet); err != nil {
+		t.Fatalf("sheet is not valid xml: %v", err)
+	}
+	if len(sheet.Rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(sheet.Rows))
+	}
+
+	cells := sheet.Rows[1].Cells
+	if len(cells) != 6 {
+		t.Fatalf("got %d cells, want 6", len(cells))
+	}
+	tests := []struct {
+		typ, value string
+	}{
+		{"inlineStr", "<Ana & María>"},
+		{"", "3"},
+		{"", "4.5"},
+		{"b", "0"},
+		{"inlineStr", "2025-03-04 15:30:00"},
+		{"", ""},
+	}
+	for i, tt := range tests {
+		value := cells[i].Value
+		if cells[i].Type == "inlineStr" {
+			value = cells[i].Inline
+		}
+		if cells[i].Type != tt.typ || value != tt.value {
+			t.Errorf(
+				"cell %d = (%q, %q), want (%q, %q)",
+				i,
+				cells[i].Type,
+				value,
+				tt.typ,
+				tt.value,
+			)
+		}
+	}
+}
+
+func TestNewWriter_UnknownFormat(t *testing.T) {
+	if _, err := NewWriter(io.Discard, "pdf"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
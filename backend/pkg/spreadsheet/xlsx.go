@@ -0,0 +1,130 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// xlsxParts are the fixed parts of a single sheet workbook. Cells are written
+// as inline strings, so no shared strings table is needed.
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+}
+
+func newXLSXWriter(w io.Writer) (Writer, error) {
+	z := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	// The sheet goes last since a zip entry must be complete before the
+	// next one starts
+	f, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	_, err = sheet.WriteString(xml.Header +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &xlsxWriter{zip: z, sheet: sheet}, nil
+}
+
+func (x *xlsxWriter) WriteRow(cells ...any) error {
+	if _, err := x.sheet.WriteString("<row>"); err != nil {
+		return err
+	}
+	for _, cell := range cells {
+		if err := x.writeCell(cell); err != nil {
+			return err
+		}
+	}
+	_, err := x.sheet.WriteString("</row>")
+	return err
+}
+
+func (x *xlsxWriter) writeCell(cell any) error {
+	switch v := cell.(type) {
+	case nil:
+		_, err := x.sheet.WriteString("<c/>")
+		return err
+	case string:
+		return x.writeString(v)
+	case int:
+		return x.writeNumber(strconv.Itoa(v))
+	case int64:
+		return x.writeNumber(strconv.FormatInt(v, 10))
+	case float64:
+		return x.writeNumber(strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		value := "0"
+		if v {
+			value = "1"
+		}
+		_, err := fmt.Fprintf(x.sheet, `<c t="b"><v>%s</v></c>`, value)
+		return err
+	case time.Time:
+		return x.writeString(formatTime(v))
+	}
+	return x.writeString(fmt.Sprint(cell))
+}
+
+func (x *xlsxWriter) writeNumber(value string) error {
+	_, err := fmt.Fprintf(x.sheet, "<c><v>%s</v></c>", value)
+	return err
+}
+
+func (x *xlsxWriter) writeString(value string) error {
+	_, err := x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	if err != nil {
+		return err
+	}
+	if err := xml.EscapeText(x.sheet, []byte(value)); err != nil {
+		return err
+	}
+	_, err = x.sheet.WriteString("</t></is></c>")
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := x.sheet.WriteString("</sheetData></worksheet>"); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+// codeAlphabet leaves out characters easily mistaken for one another
+// (0/O, 1/I/L) since codes get typed by hand
+const codeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// RandomCode returns a random uppercase code of the given length
+func RandomCode(length int) (string, error) {
+	if length <= 0 {
+		return "", errors.New("code length must be positive")
+	}
+
+	// Bytes past the last full multiple of the alphabet size are discarded so
+	// every character is equally likely
+	limit := 256 - 256%len(codeAlphabet)
+	code := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(code) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(code) < length {
+				code = append(code, codeAlphabet[int(b)%len(codeAlphabet)])
+			}
+		}
+	}
+	return string(code), nil
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRandomCode(t *testing.T) {
+	code, err := RandomCode(8)
+	if err != nil {
+		t.Fatalf("RandomCode() error = %v", err)
+	}
+	if len(code) != 8 {
+		t.Fatalf("len(RandomCode(8)) = %d, want 8", len(code))
+	}
+	for _, c := range code {
+		if !strings.ContainsRune(codeAlphabet, c) {
+			t.Fatalf("RandomCode() = %q, unexpected character %q", code, c)
+		}
+	}
+
+	other, err := RandomCode(8)
+	if err != nil {
+		t.Fatalf("RandomCode() error = %v", err)
+	}
+	if code == other {
+		t.Errorf("two RandomCode(8) calls returned the same code %q", code)
+	}
+}
+
+func TestRandomCode_InvalidLength(t *testing.T) {
+	if _, err := RandomCode(0); err == nil {
+		t.Fatal("expected error for zero length, got nil")
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 )
 
@@ -33,3 +34,25 @@ func ConvertToStrSlice(ints []int) []string {
 	}
 	return strs
 }
+
+// RoundToStep rounds v to a multiple of step. mode is "up", "down" or
+// "nearest" (the default). The result is kept to cents to drop float noise.
+func RoundToStep(v, step float64, mode string) float64 {
+	if step <= 0 {
+		step = 0.01
+	}
+
+	// Absorb float noise such as 3.0000000004 before ceiling or flooring
+	const epsilon = 1e-9
+	units := v / step
+	switch mode {
+	case "up":
+		units = math.Ceil(units - epsilon)
+	case "down":
+		units = math.Floor(units + epsilon)
+	default:
+		units = math.Round(units)
+	}
+
+	return math.Round(units*step*100) / 100
+}
@@ -221,6 +221,43 @@ func TestConvertToStrSlice(t *testing.T) {
 func archMaxInt() int { return int(^uint(0) >> 1) }
 func archMinInt() int { return -archMaxInt() - 1 }
 
+func TestRoundToStep(t *testing.T) {
+	tests := []struct {
+		name string
+		v    float64
+		step float64
+		mode string
+		want float64
+	}{
+		{"cents_nearest", 36.555, 0.01, "nearest", 36.56},
+		{"units_nearest_down", 1234.4, 1, "nearest", 1234},
+		{"units_nearest_up", 1234.5, 1, "nearest", 1235},
+		{"tens_up", 1231, 10, "up", 1240},
+		{"tens_up_exact", 1230, 10, "up", 1230},
+		{"tens_down", 1239.99, 10, "down", 1230},
+		{"float_noise_up", 0.1 * 3, 0.1, "up", 0.3},
+		{"fifty_cents_up", 36.01, 0.5, "up", 36.5},
+		{"unknown_mode_is_nearest", 12.6, 1, "sideways", 13},
+		{"invalid_step_defaults_to_cents", 1.234, 0, "nearest", 1.23},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RoundToStep(tt.v, tt.step, tt.mode)
+			if got != tt.want {
+				t.Fatalf(
+					"RoundToStep(%v, %v, %q) = %v, want %v",
+					tt.v,
+					tt.step,
+					tt.mode,
+					got,
+					tt.want,
+				)
+			}
+		})
+	}
+}
+
 func TestRoundTrip_IntsToStringsAndBack(t *testing.T) {
 	maxI, minI := archMaxInt(), archMinInt()
 
@@ -21,6 +21,30 @@ const (
 )
 
 func CompressToJPG(screenshot []byte) ([]byte, error) {
+	img, err := decodeScreenshot(screenshot)
+	if err != nil {
+		return nil, err
+	}
+	return compressImage(img)
+}
+
+// CompressAndHashJPG compresses the screenshot like CompressToJPG and also
+// returns the perceptual hash of the original image, decoding it only once.
+func CompressAndHashJPG(screenshot []byte) ([]byte, uint64, error) {
+	img, err := decodeScreenshot(screenshot)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hash := DHash(img)
+	out, err := compressImage(img)
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, hash, nil
+}
+
+func decodeScreenshot(screenshot []byte) (image.Image, error) {
 	if len(screenshot) == 0 {
 		return nil, errors.New("no screenshot data")
 	}
@@ -29,7 +53,10 @@ func CompressToJPG(screenshot []byte) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("decode: %w", err)
 	}
+	return img, nil
+}
 
+func compressImage(img image.Image) ([]byte, error) {
 	w := img.Bounds().Dx()
 	if w > _MaxImageWidth {
 		img = imaging.Resize(img, _MaxImageWidth, 0, imaging.Lanczos)
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MaskName hides most of a person's name for public listings, keeping the
+// start of the first name and the initial of the last one: "María González"
+// becomes "Mar** G.".
+func MaskName(name string) string {
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return ""
+	}
+
+	first := []rune(words[0])
+	first[0] = unicode.ToUpper(first[0])
+	keep := min(3, max(1, len(first)-1))
+	masked := string(first[:keep]) + strings.Repeat("*", len(first)-keep)
+
+	if len(words) > 1 {
+		initial := []rune(words[len(words)-1])[0]
+		masked += " " + string(unicode.ToUpper(initial)) + "."
+	}
+	return masked
+}
@@ -0,0 +1,23 @@
+package utils
+
+import "testing"
+
+func TestMaskName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"María González", "Mar** G."},
+		{"Ana", "An*"},
+		{"Jo", "J*"},
+		{"J", "J"},
+		{"  pedro  josé   pérez ", "Ped** P."},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := MaskName(tt.name); got != tt.want {
+			t.Errorf("MaskName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
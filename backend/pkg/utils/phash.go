@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"image"
+	"math/bits"
+
+	"github.com/disintegration/imaging"
+)
+
+// DHash computes the 64-bit difference hash of an image: it is shrunk to a
+// 9x8 grayscale thumbnail and each bit records whether a pixel is brighter
+// than its right neighbour. Re-encoded, resized or slightly cropped copies of
+// the same picture produce hashes a few bits apart.
+func DHash(img image.Image) uint64 {
+	thumb := imaging.Grayscale(imaging.Resize(img, 9, 8, imaging.Lanczos))
+
+	var hash uint64
+	for y := range 8 {
+		for x := range 8 {
+			left := thumb.Pix[thumb.PixOffset(x, y)]
+			right := thumb.Pix[thumb.PixOffset(x+1, y)]
+			hash <<= 1
+			if left > right {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// minHashBits is how many bits a hash needs set and unset to tell pictures
+// apart
+const minHashBits = 8
+
+// InformativeHash reports whether hash is far enough from the all zeros and
+// all ones hashes of blank, uniform or plain gradient pictures, which would
+// otherwise look alike
+func InformativeHash(hash uint64) bool {
+	return HammingDistance(hash, 0) >= minHashBits &&
+		HammingDistance(hash, ^uint64(0)) >= minHashBits
+}
+
+// HammingDistance counts the bits that differ between two hashes
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
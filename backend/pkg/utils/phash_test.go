@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestDHash_SameImageSameHash(t *testing.T) {
+	img := mkGradient(640, 480)
+	if DHash(img) != DHash(img) {
+		t.Fatal("hash must be deterministic")
+	}
+}
+
+func TestDHash_SimilarImages(t *testing.T) {
+	orig := mkNoise(64, 48, 7)
+	big := imaging.Resize(orig, 640, 480, imaging.NearestNeighbor)
+
+	tests := []struct {
+		name    string
+		variant image.Image
+	}{
+		{"reencoded_jpeg", decodeJPG(t, mustJPEGEncode(t, big, 60))},
+		{"downscaled", imaging.Resize(big, 320, 240, imaging.Lanczos)},
+		{"small_crop", imaging.Crop(big, image.Rect(6, 6, 634, 474))},
+	}
+
+	want := DHash(big)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := HammingDistance(want, DHash(tt.variant))
+			if d > 6 {
+				t.Fatalf("distance = %d, want <= 6", d)
+			}
+		})
+	}
+}
+
+func TestDHash_DifferentImages(t *testing.T) {
+	a := DHash(mkNoise(320, 240, 1))
+	b := DHash(mkNoise(320, 240, 2))
+	if d := HammingDistance(a, b); d < 10 {
+		t.Fatalf("distance = %d, want >= 10 for unrelated images", d)
+	}
+}
+
+func TestDHash_FlatImageIsZero(t *testing.T) {
+	img := mkSolid(100, 100, color.NRGBA{R: 10, G: 20, B: 30, A: 255})
+	if h := DHash(img); h != 0 {
+		t.Fatalf("hash = %064b, want 0 for a flat image", h)
+	}
+}
+
+func TestInformativeHash(t *testing.T) {
+	tests := []struct {
+		hash uint64
+		want bool
+	}{
+		{0, false},
+		{^uint64(0), false},
+		{0x7f, false},
+		{0xff, true},
+		{0xf0f0f0f0f0f0f0f0, true},
+		{^uint64(0x7f), false},
+		{DHash(mkNoise(64, 48, 7)), true},
+		{DHash(mkSolid(100, 100, color.NRGBA{R: 255, A: 255})), false},
+	}
+	for _, tt := range tests {
+		if got := InformativeHash(tt.hash); got != tt.want {
+			t.Errorf("InformativeHash(%064b) = %v, want %v", tt.hash, got, tt.want)
+		}
+	}
+}
+
+func TestHammingDistance(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		{0xFF, 0x0F, 4},
+		{0, ^uint64(0), 64},
+	}
+	for _, tt := range tests {
+		if got := HammingDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("HammingDistance(%x, %x) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCompressAndHashJPG(t *testing.T) {
+	img := mkGradient(1200, 900)
+	out, hash, err := CompressAndHashJPG(mustPNGEncode(t, img))
+	if err != nil {
+		t.Fatalf("CompressAndHashJPG: %v", err)
+	}
+	if !isJPEGMagic(out) {
+		t.Fatal("output is not a JPEG")
+	}
+	if hash != DHash(img) {
+		t.Fatalf("hash = %x, want hash of the original image %x", hash, DHash(img))
+	}
+
+	if _, _, err := CompressAndHashJPG(nil); err == nil {
+		t.Fatal("expected error for empty input")
+	}
+}
@@ -0,0 +1,200 @@
+// Package webhook signs and sends webhook deliveries, and verifies them on
+// the receiving end.
+//
+// Every delivery is a JSON POST carrying the event name, the delivery id and
+// the Unix time it was sent in headers. The signature header holds
+// "sha256=" followed by the hex HMAC-SHA256, keyed with the endpoint secret,
+// of the timestamp, a dot and the raw body.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Rifa-Event"
+	HeaderDelivery  = "X-Rifa-Delivery"
+	HeaderTimestamp = "X-Rifa-Timestamp"
+	HeaderSignature = "X-Rifa-Signature"
+)
+
+// maxResponseBody is how much of the receiver's response is kept
+const maxResponseBody = 1024
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpiredSignature = errors.New("webhook timestamp outside tolerance")
+	ErrPrivateAddress   = errors.New("webhook address isn't public")
+)
+
+// Sign returns the signature header value of body sent at timestamp
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature headers of a delivery, rejecting the ones sent
+// more than tolerance away from now
+func Verify(
+	secret string,
+	header http.Header,
+	body []byte,
+	tolerance time.Duration,
+	now time.Time,
+) error {
+	unix, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	timestamp := time.Unix(unix, 0)
+	if now.Sub(timestamp).Abs() > tolerance {
+		return ErrExpiredSignature
+	}
+
+	expected := Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(header.Get(HeaderSignature)), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Backoff returns how long to wait before retrying a delivery that failed
+// for the attempt-th time: 30s, 2m, 8m, 32m... up to 6h
+func Backoff(attempt int) time.Duration {
+	const (
+		base     = 30 * time.Second
+		maxDelay = 6 * time.Hour
+	)
+	delay := base
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 4
+	}
+	return min(delay, maxDelay)
+}
+
+// Request is a delivery of a single event to an endpoint
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID string
+	Body       []byte
+}
+
+// Response is what the endpoint answered, StatusCode is 0 when it couldn't
+// be reached
+type Response struct {
+	StatusCode int
+	Body       string
+}
+
+type Client struct {
+	http *http.Client
+	now  func() time.Time
+}
+
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{http: httpClient, now: time.Now}
+}
+
+// Send posts the signed request. It fails when the endpoint can't be reached
+// or doesn't answer with a 2xx status.
+func (c *Client) Send(ctx context.Context, r Request) (Response, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		r.URL,
+		bytes.NewReader(r.Body),
+	)
+	if err != nil {
+		return Response{}, err
+	}
+	timestamp := c.now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "rifa-webhooks/1")
+	req.Header.Set(HeaderEvent, r.Event)
+	req.Header.Set(HeaderDelivery, r.DeliveryID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(r.Secret, timestamp, r.Body))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	response := Response{StatusCode: resp.StatusCode, Body: string(body)}
+	if err != nil {
+		return response, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return response, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return response, nil
+}
+
+// PublicTransport returns a transport that only connects to public
+// addresses, refusing loopback, link-local, private and unspecified ones
+// after the host is resolved. Proxies aren't used, they'd connect on its
+// behalf.
+func PublicTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   dialPublic,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// NoRedirects is an http.Client CheckRedirect that answers with the redirect
+// itself, so a delivery isn't sent somewhere else than its endpoint
+func NoRedirects(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+func dialPublic(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addr)
+	}
+	return nil
+}
+
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified()
+}
@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+var sentAt = time.Date(2025, 3, 4, 15, 30, 0, 0, time.UTC)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"event":"purchase.created"}`)
+	header := http.Header{}
+	header.Set(HeaderTimestamp, "1741102200")
+	header.Set(HeaderSignature, Sign("secret", sentAt, body))
+
+	err := Verify("secret", header, body, 5*time.Minute, sentAt.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	tests := map[string]struct {
+		secret string
+		body   string
+		now    time.Time
+		want   error
+	}{
+		"wrong secret": {"other", string(body), sentAt, ErrInvalidSignature},
+		"tampered body": {
+			"secret",
+			`{"event":"purchase.verified"}`,
+			sentAt,
+			ErrInvalidSignature,
+		},
+		"replayed": {
+			"secret",
+			string(body),
+			sentAt.Add(time.Hour),
+			ErrExpiredSignature,
+		},
+	}
+	for name, tt := range tests {
+		err := Verify(tt.secret, header, []byte(tt.body), 5*time.Minute, tt.now)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: Verify() error = %v, want %v", name, err, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  2 * time.Minute,
+		3:  8 * time.Minute,
+		4:  32 * time.Minute,
+		6:  6 * time.Hour,
+		20: 6 * time.Hour,
+	}
+	for attempt, want := range tests {
+		if got := Backoff(attempt); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestClient_Send(t *testing.T) {
+	body := []byte(`{"id":"1","event":"webhook.test"}`)
+	var (
+		received http.Header
+		verified error
+	)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			payload, _ := io.ReadAll(r.Body)
+			received = r.Header
+			verified = Verify("secret", r.Header, payload, time.Minute, sentAt)
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte("ok"))
+		},
+	))
+	defer server.Close()
+
+	client := NewClient(server.Client())
+	client.now = func() time.Time { return sentAt }
+	resp, err := client.Send(context.Background(), Request{
+		URL:        server.URL,
+		Secret:     "secret",
+		Event:      "webhook.test",
+		DeliveryID: "d1",
+		Body:       body,
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusAccepted || resp.Body != "ok" {
+		t.Errorf("Send() = %+v, want 202 ok", resp)
+	}
+	if verified != nil {
+		t.Errorf("receiver couldn't verify the delivery: %v", verified)
+	}
+	if received.Get(HeaderEvent) != "webhook.test" ||
+		received.Get(HeaderDelivery) != "d1" ||
+		received.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected headers %v", received)
+	}
+}
+
+func TestClient_SendFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+		},
+	))
+	defer server.Close()
+
+	resp, err := NewClient(server.Client()).Send(
+		context.Background(),
+		Request{URL: server.URL, Secret: "secret", Body: []byte(`{}`)},
+	)
+	if err == nil {
+		t.Fatal("Send() succeeded on a 500")
+	}
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("StatusCode = %d, want 500", resp.StatusCode)
+	}
+
+	server.Close()
+	resp, err = NewClient(server.Client()).Send(
+		context.Background(),
+		Request{URL: server.URL, Secret: "secret", Body: []byte(`{}`)},
+	)
+	if err == nil || resp.StatusCode != 0 {
+		t.Errorf("Send() to a closed server = %+v, %v", resp, err)
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":        true,
+		"2606:4700::1111":      true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"10.0.0.5":             false,
+		"172.16.3.4":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false,
+		"fe80::1":              false,
+		"fd00::1":              false,
+		"0.0.0.0":              false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:93.184.216.34": true,
+	}
+	for addr, want := range tests {
+		if got := publicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("publicAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestClient_SendPrivate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			t.Error("delivery reached a loopback address")
+		},
+	))
+	defer server.Close()
+
+	client := NewClient(&http.Client{Transport: PublicTransport()})
+	resp, err := client.Send(
+		context.Background(),
+		Request{URL: server.URL, Secret: "secret", Body: []byte(`{}`)},
+	)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("Send() error = %v, want %v", err, ErrPrivateAddress)
+	}
+	if resp.StatusCode != 0 {
+		t.Errorf("StatusCode = %d, want 0", resp.StatusCode)
+	}
+}
+
+func TestClient_SendRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/elsewhere" {
+				t.Error("delivery followed a redirect")
+				return
+			}
+			http.Redirect(w, r, "/elsewhere", http.StatusTemporaryRedirect)
+		},
+	))
+	defer server.Close()
+
+	httpClient := server.Client()
+	httpClient.CheckRedirect = NoRedirects
+	resp, err := NewClient(httpClient).Send(
+		context.Background(),
+		Request{URL: server.URL, Secret: "secret", Body: []byte(`{}`)},
+	)
+	if err == nil {
+		t.Fatal("Send() succeeded on a redirect")
+	}
+	if resp.StatusCode != http.StatusTemporaryRedirect {
+		t.Errorf("StatusCode = %d, want 307", resp.StatusCode)
+	}
+}